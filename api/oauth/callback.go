@@ -0,0 +1,97 @@
+package oauth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/authentication"
+	"github.com/hunoz/maroon-api/login"
+	"github.com/sirupsen/logrus"
+)
+
+// Login sends the browser to the provider to sign in, and afterwards on to the redirect path.
+func Login(ctx *gin.Context) {
+	redirect, err := login.FromContext(ctx).BeginLogin(ctx.Query("redirect"))
+	if err != nil {
+		logrus.Errorf("Error starting sign-in: %s", err.Error())
+		renderPage(ctx, 500, page{Title: "Something went wrong", Message: "Sign-in could not be started. Try again later."})
+		return
+	}
+
+	ctx.Redirect(302, redirect)
+}
+
+// LogoutPath is where browsers sign out of Maroon.
+const LogoutPath = "/auth/logout"
+
+// ConfirmLogout asks the user to sign out, since signing out must be a POST from Maroon's own page.
+func ConfirmLogout(ctx *gin.Context) {
+	csrfToken, err := newCSRFToken(ctx, LogoutPath)
+	if err != nil {
+		logrus.Errorf("Error creating CSRF token: %s", err.Error())
+		renderPage(ctx, 500, page{Title: "Something went wrong", Message: "Try again later."})
+		return
+	}
+	renderPage(ctx, 200, page{Title: "Sign out", SignOut: &signOut{CSRFToken: csrfToken, Action: LogoutPath}})
+}
+
+// Logout removes the browser's session cookie. The user stays signed in with the provider.
+func Logout(ctx *gin.Context) {
+	if !checkCSRFToken(ctx, LogoutPath) {
+		renderPage(ctx, 403, page{Title: "Sign out", Message: "Your sign-out could not be confirmed. Please try again."})
+		return
+	}
+	setSessionCookie(ctx, "", -1)
+	renderPage(ctx, 200, page{Title: "Signed out", Message: "You are signed out of Maroon."})
+}
+
+// Callback is where the provider sends the browser back to after sign-in. Browser sign-ins
+// get a session cookie, and device sign-ins approve the device.
+func Callback(ctx *gin.Context) {
+	service := login.FromContext(ctx)
+	signIn, err := service.Callback(
+		ctx.Request.Context(),
+		ctx.Query("state"),
+		ctx.Query("code"),
+		ctx.Query("error"),
+	)
+	if err != nil {
+		logrus.Warnf("Sign-in failed: %s", err.Error())
+		renderPage(ctx, 400, page{Title: "Sign-in failed", Message: "You were not signed in. Please start again."})
+		return
+	}
+
+	if signIn.Attempt.UserCode != "" {
+		logrus.Infof("User '%s' approved device '%s'", signIn.Identity.Username, signIn.Attempt.UserCode)
+		renderPage(ctx, 200, page{Title: "Device approved", Message: "You are signed in as " + signIn.Identity.Username + ". You can close this window and return to your terminal."})
+		return
+	}
+
+	session, err := service.Session(signIn)
+	if err != nil {
+		logrus.Errorf("Error creating session for user '%s': %s", signIn.Identity.Username, err.Error())
+		renderPage(ctx, 500, page{Title: "Something went wrong", Message: "Sign-in could not be completed. Try again later."})
+		return
+	}
+
+	// The cookie must not outlive the ID token inside it, which may expire before the access
+	// token the provider's expires_in is about.
+	maxAge := int(time.Until(signIn.ExpiresAt).Seconds())
+	if maxAge <= 0 {
+		logrus.Warnf("ID token for user '%s' expired during sign-in", signIn.Identity.Username)
+		renderPage(ctx, 400, page{Title: "Sign-in failed", Message: "You were not signed in. Please start again."})
+		return
+	}
+
+	logrus.Infof("User '%s' signed in from a browser", signIn.Identity.Username)
+	setSessionCookie(ctx, session, maxAge)
+	ctx.Redirect(302, signIn.Attempt.RedirectTo)
+}
+
+// setSessionCookie sets the HttpOnly session cookie for maxAge seconds. A maxAge of zero keeps
+// the cookie until the browser closes, and a negative one deletes it.
+func setSessionCookie(ctx *gin.Context, value string, maxAge int) {
+	ctx.SetSameSite(http.SameSiteLaxMode)
+	ctx.SetCookie(authentication.SessionCookieName, value, maxAge, "/", "", login.FromContext(ctx).SecureCookies(), true)
+}
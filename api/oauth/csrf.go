@@ -0,0 +1,45 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/login"
+)
+
+const (
+	// csrfCookieName holds the token that forms must send back, so that other sites cannot
+	// submit them on the user's behalf.
+	csrfCookieName = "maroon_csrf"
+	csrfFormField  = "csrf_token"
+	// csrfTokenLifetime is how long the user has to submit a form.
+	csrfTokenLifetime = 10 * time.Minute
+)
+
+// newCSRFToken returns a token for a form on path and sets it as a cookie for that path.
+func newCSRFToken(ctx *gin.Context, path string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	ctx.SetSameSite(http.SameSiteStrictMode)
+	ctx.SetCookie(csrfCookieName, token, int(csrfTokenLifetime.Seconds()), path, "", login.FromContext(ctx).SecureCookies(), true)
+	return token, nil
+}
+
+// checkCSRFToken reports whether the form sent back the token in its cookie, and removes the
+// cookie so that the token is only used once.
+func checkCSRFToken(ctx *gin.Context, path string) bool {
+	cookie, err := ctx.Cookie(csrfCookieName)
+	form := ctx.PostForm(csrfFormField)
+
+	ctx.SetSameSite(http.SameSiteStrictMode)
+	ctx.SetCookie(csrfCookieName, "", -1, path, "", login.FromContext(ctx).SecureCookies(), true)
+	return err == nil && cookie != "" && subtle.ConstantTimeCompare([]byte(cookie), []byte(form)) == 1
+}
@@ -0,0 +1,147 @@
+package oauth
+
+import (
+	"errors"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/login"
+	"github.com/sirupsen/logrus"
+)
+
+// DeviceCodeGrantType is the grant_type devices poll for their tokens with.
+const DeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+type DeviceAuthorizationOutput struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type DeviceTokenOutput struct {
+	AccessToken string `json:"access_token"`
+	IdToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in,omitempty"`
+}
+
+// AuthorizeDevice is the device authorization endpoint (RFC 8628 section 3.1).
+func AuthorizeDevice(ctx *gin.Context) {
+	service := login.FromContext(ctx)
+
+	device, err := service.AuthorizeDevice(ctx.PostForm("client_id"))
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	verificationURI := service.VerificationURI()
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(200, DeviceAuthorizationOutput{
+		DeviceCode:              device.DeviceCode,
+		UserCode:                device.UserCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?" + url.Values{"user_code": {device.UserCode}}.Encode(),
+		ExpiresIn:               int(login.DeviceCodeLifetime.Seconds()),
+		Interval:                device.Interval,
+	})
+}
+
+// DeviceToken is polled by devices until their user has signed in (RFC 8628 section 3.4).
+func DeviceToken(ctx *gin.Context) {
+	if ctx.PostForm("grant_type") != DeviceCodeGrantType {
+		renderError(ctx, login.ErrUnsupportedGrantType)
+		return
+	}
+
+	device, err := login.FromContext(ctx).PollDevice(ctx.PostForm("device_code"), ctx.PostForm("client_id"))
+	if err != nil {
+		renderError(ctx, err)
+		return
+	}
+
+	logrus.Infof("Issued tokens for user '%s' to device client '%s'", device.Username, device.ClientId)
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(200, DeviceTokenOutput{
+		AccessToken: device.Tokens.AccessToken,
+		IdToken:     device.Tokens.IdToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   device.Tokens.ExpiresIn,
+	})
+}
+
+// VerifyDevice asks the user for the code shown by their device, then shows them which device
+// it is so that they only approve their own (RFC 8628 section 5.4).
+func VerifyDevice(ctx *gin.Context) {
+	userCode := ctx.Query("user_code")
+	if userCode == "" {
+		renderPage(ctx, 200, page{Title: "Sign in to a device", Form: login.DevicePath})
+		return
+	}
+
+	device, err := login.FromContext(ctx).PendingDevice(userCode)
+	if errors.Is(err, login.ErrUnknownUserCode) {
+		renderPage(ctx, 400, page{Title: "Sign in to a device", Message: err.Error(), Form: login.DevicePath})
+		return
+	} else if err != nil {
+		logrus.Errorf("Error finding device: %s", err.Error())
+		renderPage(ctx, 500, page{Title: "Something went wrong", Message: "Sign-in could not be started. Try again later."})
+		return
+	}
+
+	csrfToken, err := newCSRFToken(ctx, login.DevicePath)
+	if err != nil {
+		logrus.Errorf("Error creating CSRF token: %s", err.Error())
+		renderPage(ctx, 500, page{Title: "Something went wrong", Message: "Sign-in could not be started. Try again later."})
+		return
+	}
+
+	renderPage(ctx, 200, page{Title: "Sign in to a device", Consent: &consent{
+		ClientId:  device.ClientId,
+		UserCode:  device.UserCode,
+		CSRFToken: csrfToken,
+		Action:    login.DevicePath,
+	}})
+}
+
+// ConfirmDevice takes the user's decision from the consent page. Approved devices send the user
+// to the provider to sign in, and denied ones stop polling.
+func ConfirmDevice(ctx *gin.Context) {
+	if !checkCSRFToken(ctx, login.DevicePath) {
+		renderPage(ctx, 403, page{Title: "Sign in to a device", Message: "Your sign-in could not be confirmed. Please enter the code again.", Form: login.DevicePath})
+		return
+	}
+
+	service := login.FromContext(ctx)
+	userCode := ctx.PostForm("user_code")
+	switch ctx.PostForm("action") {
+	case "approve":
+		redirect, err := service.BeginDeviceLogin(userCode)
+		if errors.Is(err, login.ErrUnknownUserCode) {
+			renderPage(ctx, 400, page{Title: "Sign in to a device", Message: err.Error(), Form: login.DevicePath})
+			return
+		} else if err != nil {
+			logrus.Errorf("Error starting device sign-in: %s", err.Error())
+			renderPage(ctx, 500, page{Title: "Something went wrong", Message: "Sign-in could not be started. Try again later."})
+			return
+		}
+		ctx.Redirect(303, redirect)
+	case "deny":
+		err := service.DenyDevice(userCode)
+		if errors.Is(err, login.ErrUnknownUserCode) {
+			renderPage(ctx, 400, page{Title: "Sign in to a device", Message: err.Error(), Form: login.DevicePath})
+			return
+		} else if err != nil {
+			logrus.Errorf("Error denying device: %s", err.Error())
+			renderPage(ctx, 500, page{Title: "Something went wrong", Message: "The device could not be denied. Try again later."})
+			return
+		}
+		logrus.Infof("Device '%s' was denied", userCode)
+		renderPage(ctx, 200, page{Title: "Device denied", Message: "The device was not signed in. You can close this window."})
+	default:
+		renderPage(ctx, 400, page{Title: "Sign in to a device", Message: "Approve or deny the device.", Form: login.DevicePath})
+	}
+}
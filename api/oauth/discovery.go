@@ -0,0 +1,35 @@
+package oauth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/signing"
+)
+
+// JWKSPath is where the key set for Maroon's own tokens is published.
+const JWKSPath = "/.well-known/jwks.json"
+
+type OpenIDConfigurationOutput struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IdTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// OpenIDConfiguration publishes the discovery document for Maroon's own tokens, so that IAM can
+// trust Maroon as an OIDC identity provider. Maroon's issuer must be the URL it is served from.
+func OpenIDConfiguration(ctx *gin.Context) {
+	issuer := signing.FromContext(ctx).Issuer()
+	ctx.JSON(200, OpenIDConfigurationOutput{
+		Issuer:                           issuer,
+		JWKSURI:                          issuer + JWKSPath,
+		ResponseTypesSupported:           []string{"id_token"},
+		SubjectTypesSupported:            []string{"public"},
+		IdTokenSigningAlgValuesSupported: []string{signing.Algorithm},
+	})
+}
+
+// JWKS publishes the key set that verifies Maroon's own tokens.
+func JWKS(ctx *gin.Context) {
+	ctx.JSON(200, signing.FromContext(ctx).JWK())
+}
@@ -0,0 +1,29 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/login"
+	"github.com/sirupsen/logrus"
+)
+
+// renderError renders an OAuth error response. Anything that is not an OAuth error is a server error.
+func renderError(ctx *gin.Context, err error) {
+	var oauthErr *login.Error
+	if !errors.As(err, &oauthErr) {
+		logrus.Errorf("Error handling OAuth request: %s", err.Error())
+		oauthErr = &login.Error{Code: "server_error"}
+		ctx.Header("Cache-Control", "no-store")
+		ctx.JSON(http.StatusInternalServerError, oauthErr)
+		return
+	}
+
+	status := http.StatusBadRequest
+	if errors.Is(oauthErr, login.ErrInvalidClient) {
+		status = http.StatusUnauthorized
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(status, oauthErr)
+}
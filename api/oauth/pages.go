@@ -0,0 +1,69 @@
+package oauth
+
+import (
+	"html/template"
+
+	"github.com/gin-gonic/gin"
+)
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Maroon</title></head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Message}}<p>{{.Message}}</p>{{end}}
+{{if .Form}}<form method="get" action="{{.Form}}">
+<label for="user_code">Enter the code shown in your terminal</label>
+<input id="user_code" name="user_code" autocomplete="off" autofocus>
+<button type="submit">Continue</button>
+</form>{{end}}
+{{with .Consent}}<p>A device using <strong>{{.ClientId}}</strong> is asking to sign in to Maroon as you.
+Only approve it if you started this sign-in yourself and your terminal shows the code <strong>{{.UserCode}}</strong>.</p>
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="user_code" value="{{.UserCode}}">
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<button type="submit" name="action" value="approve">Approve</button>
+<button type="submit" name="action" value="deny">Deny</button>
+</form>{{end}}
+{{with .SignOut}}<form method="post" action="{{.Action}}">
+<input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+<button type="submit">Sign out</button>
+</form>{{end}}
+</body>
+</html>
+`))
+
+type page struct {
+	Title   string
+	Message string
+	// Form is where to submit a user code, if the page should ask for one.
+	Form string
+	// Consent asks the user to approve or deny a device, if set.
+	Consent *consent
+	// SignOut asks the user to sign out, if set.
+	SignOut *signOut
+}
+
+// consent shows the user which device they are approving (RFC 8628 section 5.4).
+type consent struct {
+	ClientId  string
+	UserCode  string
+	CSRFToken string
+	// Action is where to submit the decision.
+	Action string
+}
+
+func renderPage(ctx *gin.Context, status int, p page) {
+	ctx.Header("Content-Type", "text/html; charset=utf-8")
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Status(status)
+	if err := pageTemplate.Execute(ctx.Writer, p); err != nil {
+		_ = ctx.Error(err)
+	}
+}
+
+type signOut struct {
+	CSRFToken string
+	// Action is where to submit the sign-out.
+	Action string
+}
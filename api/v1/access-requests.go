@@ -0,0 +1,120 @@
+package v1
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/authentication"
+	"github.com/hunoz/maroon-api/elevation"
+	"github.com/sirupsen/logrus"
+)
+
+func renderElevationError(ctx *gin.Context, err error) {
+	var e *RestError
+	switch {
+	case errors.Is(err, elevation.ErrNotFound):
+		e = NotFoundError()
+	case errors.Is(err, elevation.ErrNotApprover), errors.Is(err, elevation.ErrSelfApproval):
+		e = ForbiddenError()
+	case errors.Is(err, elevation.ErrAlreadyDecided):
+		e = ConflictError()
+	case errors.Is(err, elevation.ErrInvalidDuration):
+		e = BadRequestError()
+	default:
+		logrus.Errorf("Error handling access request: %s", err.Error())
+		e = InternalServerError()
+	}
+	renderResponse(ctx, e.Status, e)
+}
+
+// CreateAccessRequest files a just-in-time request for elevated access to an account.
+func CreateAccessRequest(ctx *gin.Context) {
+	input := CreateAccessRequestInput{}
+	identity := authentication.GetIdentity(ctx)
+
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		err := parseBindingError(err)
+		renderResponse(ctx, err.Status, err)
+		return
+	}
+
+	if !isValidAccessType(input.AccessType) {
+		renderResponse(ctx, 400, BadRequestError())
+		return
+	}
+
+	req, err := elevation.FromContext(ctx).Submit(
+		identity,
+		input.AccountId,
+		string(input.AccessType),
+		accessTypeRoleArn(input.AccountId, input.AccessType),
+		input.Justification,
+		input.Duration,
+	)
+	if err != nil {
+		renderElevationError(ctx, err)
+		return
+	}
+
+	logrus.Infof("User '%s' from '%s' requested %s access to account '%s' (%s)", identity.Username, identity.Issuer, req.AccessType, req.AccountId, req.Id)
+	renderResponse(ctx, 201, AccessRequestOutput{Request: *req})
+}
+
+// ListAccessRequests returns every access request to approvers and the caller's own requests to everyone else.
+func ListAccessRequests(ctx *gin.Context) {
+	service := elevation.FromContext(ctx)
+	identity := authentication.GetIdentity(ctx)
+	requester := identity
+	if service.IsApprover(identity) {
+		requester = nil
+	}
+
+	requests, err := service.List(requester)
+	if err != nil {
+		renderElevationError(ctx, err)
+		return
+	}
+
+	renderResponse(ctx, 200, ListAccessRequestsOutput{AccessRequests: requests})
+}
+
+// GetAccessRequest returns a single access request to its requester or an approver.
+func GetAccessRequest(ctx *gin.Context) {
+	service := elevation.FromContext(ctx)
+	identity := authentication.GetIdentity(ctx)
+
+	req, err := service.Get(ctx.Param("id"))
+	if err != nil {
+		renderElevationError(ctx, err)
+		return
+	}
+	if !req.IsRequestedBy(identity.Issuer, identity.Username) && !service.IsApprover(identity) {
+		renderElevationError(ctx, elevation.ErrNotFound)
+		return
+	}
+
+	renderResponse(ctx, 200, AccessRequestOutput{Request: *req})
+}
+
+// ApproveAccessRequest turns a pending access request into a time-boxed grant.
+func ApproveAccessRequest(ctx *gin.Context) {
+	decideAccessRequest(ctx, true)
+}
+
+// DenyAccessRequest rejects a pending access request.
+func DenyAccessRequest(ctx *gin.Context) {
+	decideAccessRequest(ctx, false)
+}
+
+func decideAccessRequest(ctx *gin.Context, approve bool) {
+	identity := authentication.GetIdentity(ctx)
+
+	req, err := elevation.FromContext(ctx).Decide(ctx.Param("id"), identity, approve)
+	if err != nil {
+		renderElevationError(ctx, err)
+		return
+	}
+
+	logrus.Infof("User '%s' decided access request '%s' from '%s': %s", identity.Username, req.Id, req.Requester, req.Status)
+	renderResponse(ctx, 200, AccessRequestOutput{Request: *req})
+}
@@ -0,0 +1,135 @@
+package v1
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/apitoken"
+	"github.com/sirupsen/logrus"
+)
+
+func renderApiTokenError(ctx *gin.Context, err error) {
+	var e *RestError
+	switch {
+	case errors.Is(err, apitoken.ErrNotFound):
+		e = NotFoundError()
+	case errors.Is(err, apitoken.ErrAlreadyRevoked):
+		e = ConflictError()
+	case errors.Is(err, apitoken.ErrInvalidDuration), errors.Is(err, apitoken.ErrInvalidScope):
+		e = BadRequestError()
+	default:
+		logrus.Errorf("Error handling API token: %s", err.Error())
+		e = InternalServerError()
+	}
+	renderResponse(ctx, e.Status, e)
+}
+
+// apiTokenService returns the API token service, or renders a 404 and returns nil if Maroon
+// cannot issue tokens.
+func apiTokenService(ctx *gin.Context) *apitoken.Service {
+	service := apitoken.FromContext(ctx)
+	if service == nil {
+		err := NotFoundError()
+		err.Reason = "api_tokens_disabled"
+		renderResponse(ctx, err.Status, err)
+	}
+	return service
+}
+
+// CreateApiToken issues a scoped API token on behalf of a policy admin. The token is only
+// returned in this response.
+func CreateApiToken(ctx *gin.Context) {
+	if !requireAdmin(ctx) {
+		return
+	}
+	service := apiTokenService(ctx)
+	if service == nil {
+		return
+	}
+
+	input := CreateApiTokenInput{}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		err := parseBindingError(err)
+		renderResponse(ctx, err.Status, err)
+		return
+	}
+
+	scope := apitoken.Scope{AccountIds: input.AccountIds, RoleArnPatterns: input.RoleArnPatterns}
+	for _, accessType := range input.AccessTypes {
+		if !isValidAccessType(accessType) {
+			renderResponse(ctx, 400, BadRequestError())
+			return
+		}
+		scope.AccessTypes = append(scope.AccessTypes, string(accessType))
+	}
+
+	username := callerRequest(ctx).Username
+	token, signed, err := service.Issue(input.Name, input.Description, scope, time.Duration(input.Duration)*time.Second, username)
+	if err != nil {
+		renderApiTokenError(ctx, err)
+		return
+	}
+
+	logrus.Infof("User '%s' issued API token '%s' for '%s', expiring at %s", username, token.Id, token.Name, token.ExpiresAt)
+	renderResponse(ctx, 201, ApiTokenOutput{Token: *token, Value: signed})
+}
+
+// ListApiTokens returns the records of every API token to policy admins.
+func ListApiTokens(ctx *gin.Context) {
+	if !requireAdmin(ctx) {
+		return
+	}
+	service := apiTokenService(ctx)
+	if service == nil {
+		return
+	}
+
+	tokens, err := service.List()
+	if err != nil {
+		renderApiTokenError(ctx, err)
+		return
+	}
+
+	renderResponse(ctx, 200, ListApiTokensOutput{ApiTokens: tokens})
+}
+
+// GetApiToken returns the record of a single API token to policy admins.
+func GetApiToken(ctx *gin.Context) {
+	if !requireAdmin(ctx) {
+		return
+	}
+	service := apiTokenService(ctx)
+	if service == nil {
+		return
+	}
+
+	token, err := service.Get(ctx.Param("id"))
+	if err != nil {
+		renderApiTokenError(ctx, err)
+		return
+	}
+
+	renderResponse(ctx, 200, ApiTokenOutput{Token: *token})
+}
+
+// RevokeApiToken stops an API token from being accepted on behalf of a policy admin.
+func RevokeApiToken(ctx *gin.Context) {
+	if !requireAdmin(ctx) {
+		return
+	}
+	service := apiTokenService(ctx)
+	if service == nil {
+		return
+	}
+
+	username := callerRequest(ctx).Username
+	token, err := service.Revoke(ctx.Param("id"), username)
+	if err != nil {
+		renderApiTokenError(ctx, err)
+		return
+	}
+
+	logrus.Infof("User '%s' revoked API token '%s' for '%s'", username, token.Id, token.Name)
+	renderResponse(ctx, 200, ApiTokenOutput{Token: *token})
+}
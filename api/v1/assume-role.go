@@ -1,80 +1,44 @@
 package v1
 
 import (
-	"context"
-	"encoding/json"
-	"fmt"
 	"regexp"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
-	"github.com/aws/aws-sdk-go-v2/service/sts/types"
 	"github.com/gin-gonic/gin"
-	"github.com/pkg/errors"
+	"github.com/hunoz/maroon-api/authentication"
+	"github.com/hunoz/maroon-api/authorization"
+	"github.com/hunoz/maroon-api/federation"
 	"github.com/sirupsen/logrus"
 )
 
-type IamCredentials struct {
-	AccessKeyId     string `json:"AccessKeyId" binding:"required"`
-	SecretAccessKey string `json:"SecretAccessKey" binding:"required"`
-}
-
-func getIamCredentials() (*IamCredentials, error) {
-	cfg, _ := config.LoadDefaultConfig(context.TODO())
-	client := secretsmanager.NewFromConfig(cfg)
-
-	output, err := client.GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{
-		SecretId: aws.String("MaroonApiIamUser"),
-	})
-	if err != nil {
-		logrus.Errorf("Error getting IAM user credentials: %s", err.Error())
-		return nil, errors.Wrap(err, "Error getting IAM user credentials")
-	}
-
-	var credentials IamCredentials
+var roleArnRegex = regexp.MustCompile(`^arn:aws:iam::\d{12}:role/[0-9A-Za-z_+=,.@-]{1,64}$`)
 
-	if err = json.Unmarshal([]byte(*output.SecretString), &credentials); err != nil {
-		logrus.Errorf("Error parsing IAM user credentials: %s", err.Error())
-		return nil, errors.Wrap(err, "Error parsing IAM user credentials")
-	}
-
-	return &credentials, nil
-}
+// assumeRole assumes the role for the caller through the request's role broker. The session is
+// named after the caller, who is also set as its source identity, and tagged with the caller's
+// identity when session tags are configured.
+func assumeRole(ctx *gin.Context, roleArn string, accessType AccessType, duration int32) (*federation.Session, error) {
+	broker := federation.FromContext(ctx)
+	identity := authentication.GetIdentity(ctx)
+	tags, transitiveTagKeys := broker.SessionTags().For(identity, authentication.GetClaims(ctx), string(accessType))
 
-func assumeRole(roleArn string, username string, duration int32, cfg *aws.Config) (*types.Credentials, error) {
-	iamCredentials, err := getIamCredentials()
+	roleSessionName, err := broker.RoleSessionName(identity, string(accessType), accountIdFromRoleArn(roleArn))
 	if err != nil {
 		return nil, err
 	}
-	var conf aws.Config
-	if cfg == nil {
-		conf, err = config.LoadDefaultConfig(
-			context.TODO(),
-			config.WithCredentialsProvider(
-				credentials.NewStaticCredentialsProvider(iamCredentials.AccessKeyId, iamCredentials.SecretAccessKey, ""),
-			),
-		)
-	} else {
-		conf = *cfg
-	}
-	if err != nil {
-		return nil, errors.Wrap(err, "Error creating config")
+
+	input := &sts.AssumeRoleInput{
+		RoleArn:           &roleArn,
+		DurationSeconds:   &duration,
+		RoleSessionName:   aws.String(roleSessionName),
+		Tags:              tags,
+		TransitiveTagKeys: transitiveTagKeys,
 	}
-	client := sts.NewFromConfig(conf)
-	output, err := client.AssumeRole(context.TODO(), &sts.AssumeRoleInput{
-		RoleArn:         &roleArn,
-		DurationSeconds: &duration,
-		RoleSessionName: aws.String(fmt.Sprintf("MaroonApi-%s", username)),
-	})
-	if err != nil {
-		return nil, errors.Wrap(err, "Error Assuming Role")
+	if sourceIdentity := broker.SourceIdentity(identity); sourceIdentity != "" {
+		input.SourceIdentity = aws.String(sourceIdentity)
 	}
-
-	return output.Credentials, nil
+	return broker.AssumeRole(ctx.Request.Context(), input)
 }
 
 func toCamelCase(str string) string {
@@ -84,7 +48,6 @@ func toCamelCase(str string) string {
 
 func AssumeRole(ctx *gin.Context) {
 	input := AssumeRoleInput{}
-	username, _ := ctx.Get("username")
 
 	if err := ctx.ShouldBindQuery(&input); err != nil {
 		err := parseBindingError(err)
@@ -92,15 +55,23 @@ func AssumeRole(ctx *gin.Context) {
 		return
 	}
 
-	matches, _ := regexp.MatchString(`^arn:aws:iam::\d{12}:role/[0-9A-Za-z_+=,.@-]{1,64}$`, input.RoleArn)
-	if !matches {
+	if !roleArnRegex.MatchString(input.RoleArn) {
 		logrus.Errorf("String does not match role ARN regex: %s", input.RoleArn)
 		err := BadRequestError()
 		renderResponse(ctx, err.Status, err)
 		return
 	}
 
-	credentials, err := assumeRole(input.RoleArn, username.(string), input.SessionDuration, nil)
+	if !authorize(ctx, authorization.Request{
+		AccountId:  accountIdFromRoleArn(input.RoleArn),
+		RoleArn:    input.RoleArn,
+		AccessType: string(roleArnAccessType(input.RoleArn)),
+		Duration:   input.SessionDuration,
+	}) {
+		return
+	}
+
+	session, err := assumeRole(ctx, input.RoleArn, roleArnAccessType(input.RoleArn), input.SessionDuration)
 	if err != nil {
 		logrus.Errorf("Error fetching role credentials: %s", err.Error())
 		var e *RestError
@@ -114,9 +85,13 @@ func AssumeRole(ctx *gin.Context) {
 	}
 
 	renderResponse(ctx, 200, AssumeRoleOutput{
-		AccessKeyId:     *credentials.AccessKeyId,
-		SecretAccessKey: *credentials.SecretAccessKey,
-		SessionToken:    *credentials.SessionToken,
-		Expiration:      *credentials.Expiration,
+		AccessKeyId:     *session.Credentials.AccessKeyId,
+		SecretAccessKey: *session.Credentials.SecretAccessKey,
+		SessionToken:    *session.Credentials.SessionToken,
+		Expiration:      *session.Credentials.Expiration,
+		SessionDuration: session.Duration,
+		RoleChain:       session.Chain,
+		RoleSessionName: session.RoleSessionName,
+		SourceIdentity:  session.SourceIdentity,
 	})
 }
@@ -0,0 +1,39 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/authentication"
+)
+
+// AuthenticationErrorMiddleware renders the failures authentication.JWTMiddleware aborts with
+// in the standard error envelope. It must be registered before JWTMiddleware.
+func AuthenticationErrorMiddleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		for _, ginErr := range ctx.Errors {
+			var authErr *authentication.Error
+			if errors.As(ginErr.Err, &authErr) {
+				renderResponse(ctx, authErr.Status, authenticationError(authErr))
+				return
+			}
+		}
+	}
+}
+
+func authenticationError(authErr *authentication.Error) *RestError {
+	var restErr *RestError
+	switch authErr.Status {
+	case http.StatusBadRequest:
+		restErr = BadRequestError()
+	case http.StatusServiceUnavailable:
+		restErr = ServiceUnavailableError()
+	default:
+		restErr = UnauthorizedError()
+	}
+	restErr.Reason = authErr.Reason
+	return restErr
+}
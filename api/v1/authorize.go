@@ -0,0 +1,66 @@
+package v1
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/authentication"
+	"github.com/hunoz/maroon-api/authorization"
+	"github.com/sirupsen/logrus"
+)
+
+// authorize evaluates the request for the caller and renders a 403 if it is denied, or a 401
+// with a step-up challenge if the caller must authenticate again.
+func authorize(ctx *gin.Context, req authorization.Request) bool {
+	caller := callerRequest(ctx)
+	req.PrincipalType = caller.PrincipalType
+	req.Username = caller.Username
+	req.Groups = caller.Groups
+	req.Issuer = caller.Issuer
+	req.Claims = caller.Claims
+	req.Authentication = caller.Authentication
+	decision := authorization.FromContext(ctx).Authorize(req)
+	if decision.StepUp != nil {
+		logrus.Warnf("Step-up authentication required for user '%s': %s", req.Username, decision.Reason)
+		ctx.Header("WWW-Authenticate", stepUpChallenge(decision))
+		err := UnauthorizedError()
+		err.Reason = "insufficient_user_authentication"
+		renderResponse(ctx, err.Status, err)
+		return false
+	}
+	if !decision.Allowed {
+		logrus.Warnf("Denied access for user '%s': %s", req.Username, decision.Reason)
+		err := ForbiddenError()
+		renderResponse(ctx, err.Status, err)
+		return false
+	}
+	return true
+}
+
+// callerRequest returns a request with the identity and authentication of the caller.
+func callerRequest(ctx *gin.Context) authorization.Request {
+	identity := authentication.GetIdentity(ctx)
+	return authorization.Request{
+		PrincipalType:  string(identity.Type),
+		Username:       identity.Username,
+		Groups:         identity.Groups,
+		Issuer:         identity.Issuer,
+		Claims:         authentication.GetClaims(ctx),
+		Authentication: authorization.AuthenticationFromContext(ctx),
+	}
+}
+
+// stepUpChallenge follows RFC 9470, telling the client how to authenticate again.
+func stepUpChallenge(decision authorization.Decision) string {
+	challenge := fmt.Sprintf(`Bearer error="insufficient_user_authentication", error_description=%q`, decision.Reason)
+	if decision.StepUp.MaxAuthAge > 0 {
+		challenge += fmt.Sprintf(", max_age=%d", decision.StepUp.MaxAuthAge)
+	}
+	return challenge
+}
+
+// accountIdFromRoleArn expects an ARN that has already been validated against the role ARN regex.
+func accountIdFromRoleArn(roleArn string) string {
+	return strings.Split(roleArn, ":")[4]
+}
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/authorization"
 	"github.com/sirupsen/logrus"
 )
 
@@ -40,11 +41,31 @@ func isValidAccessType(accessType AccessType) bool {
 	return false
 }
 
+func accessTypeRoleName(accessType AccessType) string {
+	if accessType == AccessTypeAdmin {
+		return "MaroonApiAdminAccessRole-DO-NOT-DELETE"
+	}
+	return "MaroonApiReadOnlyAccessRole-DO-NOT-DELETE"
+}
+
+// roleArnAccessType returns the access type whose role the ARN refers to, or an empty access type.
+func roleArnAccessType(roleArn string) AccessType {
+	for _, accessType := range AccessTypes {
+		if strings.HasSuffix(roleArn, ":role/"+accessTypeRoleName(AccessType(accessType))) {
+			return AccessType(accessType)
+		}
+	}
+	return ""
+}
+
+func accessTypeRoleArn(accountId string, accessType AccessType) string {
+	return fmt.Sprintf("arn:aws:iam::%s:role/%s", accountId, accessTypeRoleName(accessType))
+}
+
 // https://docs.aws.amazon.com/IAM/latest/UserGuide/id_roles_providers_enable-console-custom-url.html#STSConsoleLink_programPython
 // This required that you be using IAM user credentials. Perhaps fetching from Secrets Manager then assuming role?
 func GetConsoleUrl(ctx *gin.Context) {
 	input := GetConsoleUrlInput{}
-	username, _ := ctx.Get("username")
 
 	if err := ctx.ShouldBindQuery(&input); err != nil {
 		err := parseBindingError(err)
@@ -57,14 +78,17 @@ func GetConsoleUrl(ctx *gin.Context) {
 		return
 	}
 
-	var iamRoleName string
-	if input.AccessType == AccessTypeAdmin {
-		iamRoleName = "MaroonApiAdminAccessRole-DO-NOT-DELETE"
-	} else {
-		iamRoleName = "MaroonApiReadOnlyAccessRole-DO-NOT-DELETE"
+	if !authorize(ctx, authorization.Request{
+		AccountId:  input.AccountId,
+		AccessType: string(input.AccessType),
+		Duration:   int32(input.Duration),
+	}) {
+		return
 	}
 
-	credentials, err := assumeRole(fmt.Sprintf("arn:aws:iam::%s:role/%s", input.AccountId, iamRoleName), username.(string), int32(input.Duration), nil)
+	iamRoleName := accessTypeRoleName(input.AccessType)
+
+	session, err := assumeRole(ctx, accessTypeRoleArn(input.AccountId, input.AccessType), input.AccessType, int32(input.Duration))
 	if err != nil {
 		logrus.Errorf("Error assuming role '%s': %s", iamRoleName, err.Error())
 		var e *RestError
@@ -78,9 +102,9 @@ func GetConsoleUrl(ctx *gin.Context) {
 	}
 
 	urlCredentials := UrlCredentials{
-		SessionId:    *credentials.AccessKeyId,
-		SessionKey:   *credentials.SecretAccessKey,
-		SessionToken: *credentials.SessionToken,
+		SessionId:    *session.Credentials.AccessKeyId,
+		SessionKey:   *session.Credentials.SecretAccessKey,
+		SessionToken: *session.Credentials.SessionToken,
 	}
 
 	var jsonCredentials []byte
@@ -91,7 +115,7 @@ func GetConsoleUrl(ctx *gin.Context) {
 		return
 	}
 
-	federationUrlParameters := fmt.Sprintf("?Action=getSigninToken&SessionDuration=%v&Session=%s", input.Duration, url.QueryEscape(string(jsonCredentials)))
+	federationUrlParameters := fmt.Sprintf("?Action=getSigninToken&SessionDuration=%v&Session=%s", session.Duration, url.QueryEscape(string(jsonCredentials)))
 
 	federationUrl := fmt.Sprintf("https://signin.aws.amazon.com/federation%s", federationUrlParameters)
 
@@ -132,6 +156,7 @@ func GetConsoleUrl(ctx *gin.Context) {
 	)
 
 	renderResponse(ctx, 200, GetConsoleUrlOutput{
-		ConsoleUrl: federationUrl,
+		ConsoleUrl:      federationUrl,
+		SessionDuration: session.Duration,
 	})
 }
@@ -2,6 +2,7 @@ package v1
 
 import (
 	"encoding/xml"
+	"errors"
 	"net/http"
 
 	"github.com/go-playground/validator/v10"
@@ -11,10 +12,15 @@ import (
 var InvalidRequestExceptionMessage = "Bad Request"
 var UnauthorizedExceptionMessage = "Unauthorized"
 var ForbiddenExceptionMessage = "Forbidden"
+var NotFoundExceptionMessage = "Not Found"
+var ConflictExceptionMessage = "Conflict"
 var InternalServerExceptionMessage = "Internal Server Error"
+var ServiceUnavailableExceptionMessage = "Service Unavailable"
 
 type Error struct {
 	Message string `json:"message"`
+	// Reason is a machine-readable explanation of the error, if there is one.
+	Reason string `json:"reason,omitempty" xml:",omitempty"`
 }
 
 type JSONError struct {
@@ -55,6 +61,24 @@ func ForbiddenError() *RestError {
 	}
 }
 
+func NotFoundError() *RestError {
+	return &RestError{
+		Status: http.StatusNotFound,
+		Error: Error{
+			Message: NotFoundExceptionMessage,
+		},
+	}
+}
+
+func ConflictError() *RestError {
+	return &RestError{
+		Status: http.StatusConflict,
+		Error: Error{
+			Message: ConflictExceptionMessage,
+		},
+	}
+}
+
 func InternalServerError() *RestError {
 	return &RestError{
 		Status: http.StatusInternalServerError,
@@ -64,9 +88,28 @@ func InternalServerError() *RestError {
 	}
 }
 
+func ServiceUnavailableError() *RestError {
+	return &RestError{
+		Status: http.StatusServiceUnavailable,
+		Error: Error{
+			Message: ServiceUnavailableExceptionMessage,
+		},
+	}
+}
+
+// parseBindingError returns a 400 for a request that failed validation, or that could not be
+// bound at all, such as a malformed or empty body.
 func parseBindingError(err error) *RestError {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		logrus.Errorf("Failed to bind request: %s", err.Error())
+		restErr := BadRequestError()
+		restErr.Reason = "malformed_request"
+		return restErr
+	}
+
 	fieldErrors := make(map[string]string)
-	for _, v := range err.(validator.ValidationErrors) {
+	for _, v := range validationErrors {
 		fieldErrors[toCamelCase(v.Field())] = v.Tag()
 	}
 	logrus.Errorf("Failed to bind to query: %+v", fieldErrors)
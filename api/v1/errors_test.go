@@ -0,0 +1,47 @@
+package v1
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCreateAccessRequestMalformedBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/access-requests", CreateAccessRequest)
+
+	for name, body := range map[string]string{
+		"truncated":     "{",
+		"empty":         "",
+		"wrong type":    `{"accountId": 1}`,
+		"not an object": "[]",
+		"missing field": "{}",
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/access-requests", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s body: status %d, want %d", name, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestParseBindingError(t *testing.T) {
+	for name, err := range map[string]error{
+		"syntax error": json.Unmarshal([]byte("{"), &struct{}{}),
+		"type error":   json.Unmarshal([]byte(`{"a": 1}`), &struct{ A string }{}),
+		"empty body":   io.EOF,
+	} {
+		restErr := parseBindingError(err)
+		if restErr.Status != http.StatusBadRequest || restErr.Reason != "malformed_request" {
+			t.Errorf("%s: parseBindingError() = %d %q, want 400 malformed_request", name, restErr.Status, restErr.Reason)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/authorization"
+	"github.com/sirupsen/logrus"
+)
+
+// ExplainAuthorization runs the same policy decision as the credential handlers and reports
+// the matched rule, the reason for a denial and the longest session that would be allowed.
+// Policy admins may pass an issuer with a username and groups to explain the decision for another user, which
+// is evaluated as if that user had authenticated the way the caller did.
+func ExplainAuthorization(ctx *gin.Context) {
+	input := ExplainAuthorizationInput{}
+
+	if err := ctx.ShouldBindQuery(&input); err != nil {
+		err := parseBindingError(err)
+		renderResponse(ctx, err.Status, err)
+		return
+	}
+
+	if input.RoleArn != "" && !roleArnRegex.MatchString(input.RoleArn) {
+		logrus.Errorf("String does not match role ARN regex: %s", input.RoleArn)
+		err := BadRequestError()
+		renderResponse(ctx, err.Status, err)
+		return
+	}
+	if input.AccountId == "" && input.RoleArn != "" {
+		input.AccountId = accountIdFromRoleArn(input.RoleArn)
+	}
+	if input.AccountId == "" || (input.AccessType != "" && !isValidAccessType(input.AccessType)) {
+		err := BadRequestError()
+		renderResponse(ctx, err.Status, err)
+		return
+	}
+
+	authorizer := authorization.FromContext(ctx)
+	req := callerRequest(ctx)
+
+	if input.Username != "" || len(input.Groups) > 0 || input.Issuer != "" {
+		// Users and groups are namespaced by issuer, so they mean nothing without one
+		if input.Issuer == "" {
+			err := BadRequestError()
+			renderResponse(ctx, err.Status, err)
+			return
+		}
+		if !authorizer.IsAdmin(req) {
+			logrus.Warnf("User '%s' is not allowed to explain decisions for other users", req.Username)
+			err := ForbiddenError()
+			renderResponse(ctx, err.Status, err)
+			return
+		}
+		req.PrincipalType = authorization.PrincipalTypeUser
+		req.Username = input.Username
+		req.Groups = input.Groups
+		req.Issuer = input.Issuer
+		req.Claims = nil
+	}
+
+	accessType := input.AccessType
+	if accessType == "" {
+		accessType = roleArnAccessType(input.RoleArn)
+	}
+
+	req.AccountId = input.AccountId
+	req.RoleArn = input.RoleArn
+	req.AccessType = string(accessType)
+	req.Duration = input.Duration
+	decision := authorizer.Authorize(req)
+
+	renderResponse(ctx, 200, ExplainAuthorizationOutput{
+		Username:             req.Username,
+		Groups:               req.Groups,
+		Issuer:               req.Issuer,
+		Allowed:              decision.Allowed,
+		MatchedRule:          decision.Rule,
+		MatchedGrantId:       decision.GrantId,
+		Reason:               decision.Reason,
+		StepUpRequired:       decision.StepUp != nil,
+		EffectiveMaxDuration: decision.MaxDuration,
+	})
+}
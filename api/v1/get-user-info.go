@@ -1,23 +1,19 @@
 package v1
 
 import (
-	"fmt"
-
 	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/authentication"
 )
 
 func GetUserInfo(ctx *gin.Context) {
-	username := ctx.GetString("username")
-	userGroups, _ := ctx.Get("groups")
-
-	groups := make([]string, len(userGroups.([]interface{})))
-
-	for i, v := range userGroups.([]interface{}) {
-		groups[i] = fmt.Sprint(v)
-	}
+	identity := authentication.GetIdentity(ctx)
 
 	renderResponse(ctx, 200, GetUserInfoOutput{
-		Username: username,
-		Groups:   groups,
+		Type:     string(identity.Type),
+		Subject:  identity.Subject,
+		Username: identity.Username,
+		Email:    identity.Email,
+		Groups:   identity.Groups,
+		Issuer:   identity.Issuer,
 	})
 }
@@ -1,5 +1,7 @@
 package v1
 
+import "time"
+
 type AssumeRoleInput struct {
 	RoleArn         string `json:"roleArn" binding:"required" form:"roleArn"`
 	SessionDuration int32  `json:"sessionDuration" binding:"required,numeric,min=900,max=43200" form:"sessionDuration"`
@@ -10,3 +12,37 @@ type GetConsoleUrlInput struct {
 	AccessType AccessType `json:"accessType" binding:"required" form:"accessType"`
 	Duration   int        `json:"duration" binding:"required,numeric,min=900,max=43200" form:"duration"`
 }
+
+type ExplainAuthorizationInput struct {
+	AccountId  string     `json:"accountId" binding:"omitempty,numeric,len=12" form:"accountId"`
+	RoleArn    string     `json:"roleArn" form:"roleArn"`
+	AccessType AccessType `json:"accessType" form:"accessType"`
+	Duration   int32      `json:"duration" binding:"omitempty,numeric,min=900,max=43200" form:"duration"`
+	Username   string     `json:"username" form:"username"`
+	Groups     []string   `json:"groups" form:"groups"`
+	Issuer     string     `json:"issuer" form:"issuer"`
+}
+
+type CreateAccessRequestInput struct {
+	AccountId     string     `json:"accountId" binding:"required,numeric,len=12"`
+	AccessType    AccessType `json:"accessType" binding:"required"`
+	Justification string     `json:"justification" binding:"required,max=1024"`
+	Duration      int32      `json:"duration" binding:"required,numeric,min=900,max=43200"`
+}
+
+type CreateRevocationInput struct {
+	Issuer       string    `json:"issuer"`
+	TokenId      string    `json:"tokenId"`
+	Subject      string    `json:"subject"`
+	IssuedBefore time.Time `json:"issuedBefore"`
+	Reason       string    `json:"reason" binding:"required,max=1024"`
+}
+
+type CreateApiTokenInput struct {
+	Name            string       `json:"name" binding:"required,max=64"`
+	Description     string       `json:"description" binding:"max=1024"`
+	AccountIds      []string     `json:"accountIds" binding:"required,min=1"`
+	RoleArnPatterns []string     `json:"roleArnPatterns"`
+	AccessTypes     []AccessType `json:"accessTypes"`
+	Duration        int64        `json:"duration" binding:"required,numeric,min=900"`
+}
@@ -5,6 +5,10 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/apitoken"
+	"github.com/hunoz/maroon-api/authorization"
+	"github.com/hunoz/maroon-api/elevation"
+	"github.com/hunoz/maroon-api/revocation"
 )
 
 type XMLResponse struct {
@@ -21,17 +25,72 @@ type AssumeRoleOutput struct {
 	SecretAccessKey string `json:"secretAccessKey"`
 	SessionToken    string `json:"sessionToken"`
 	Expiration      time.Time
+	// SessionDuration is the duration the session was issued for, which STS caps for chained roles.
+	SessionDuration int32    `json:"sessionDuration"`
+	RoleChain       []string `json:"roleChain,omitempty"`
+	RoleSessionName string   `json:"roleSessionName"`
+	SourceIdentity  string   `json:"sourceIdentity,omitempty"`
 }
 
 type GetConsoleUrlOutput struct {
 	XMLResponse
-	ConsoleUrl string `json:"consoleUrl"`
+	ConsoleUrl      string `json:"consoleUrl"`
+	SessionDuration int32  `json:"sessionDuration"`
 }
 
 type GetUserInfoOutput struct {
 	XMLResponse
+	Type     string   `json:"type" type:"string"`
+	Subject  string   `json:"subject" type:"string"`
 	Username string   `json:"username" type:"string"`
+	Email    string   `json:"email" type:"string"`
 	Groups   []string `json:"groups" type:"slice"`
+	Issuer   string   `json:"issuer" type:"string"`
+}
+
+type ExplainAuthorizationOutput struct {
+	XMLResponse
+	Username             string              `json:"username"`
+	Groups               []string            `json:"groups"`
+	Issuer               string              `json:"issuer"`
+	Allowed              bool                `json:"allowed"`
+	MatchedRule          *authorization.Rule `json:"matchedRule"`
+	MatchedGrantId       string              `json:"matchedGrantId,omitempty"`
+	Reason               string              `json:"reason,omitempty"`
+	StepUpRequired       bool                `json:"stepUpRequired"`
+	EffectiveMaxDuration int32               `json:"effectiveMaxDuration"`
+}
+
+type AccessRequestOutput struct {
+	XMLResponse
+	elevation.Request
+}
+
+type ListAccessRequestsOutput struct {
+	XMLResponse
+	AccessRequests []*elevation.Request `json:"accessRequests"`
+}
+
+type RevocationOutput struct {
+	XMLResponse
+	revocation.Entry
+}
+
+type ListRevocationsOutput struct {
+	XMLResponse
+	Revocations []*revocation.Entry `json:"revocations"`
+}
+
+type ApiTokenOutput struct {
+	XMLResponse
+	apitoken.Token
+	// Value is the token itself, which is only returned when the token is created.
+	Value string `json:"token,omitempty" xml:"Token,omitempty"`
+}
+
+type ListApiTokensOutput struct {
+	XMLResponse
+	ApiTokens []*apitoken.Token `json:"apiTokens"`
 }
 
 func renderResponse(ctx *gin.Context, statusCode int, body interface{}) {
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/authorization"
+	"github.com/hunoz/maroon-api/revocation"
+	"github.com/sirupsen/logrus"
+)
+
+func renderRevocationError(ctx *gin.Context, err error) {
+	var e *RestError
+	switch {
+	case errors.Is(err, revocation.ErrNotFound):
+		e = NotFoundError()
+	case errors.Is(err, revocation.ErrInvalidEntry):
+		e = BadRequestError()
+	default:
+		logrus.Errorf("Error handling revocation: %s", err.Error())
+		e = InternalServerError()
+	}
+	renderResponse(ctx, e.Status, e)
+}
+
+// requireAdmin renders a 403 and returns false unless the caller is a policy admin.
+func requireAdmin(ctx *gin.Context) bool {
+	req := callerRequest(ctx)
+	if !authorization.FromContext(ctx).IsAdmin(req) {
+		logrus.Warnf("User '%s' is not a policy admin", req.Username)
+		err := ForbiddenError()
+		renderResponse(ctx, err.Status, err)
+		return false
+	}
+	return true
+}
+
+// CreateRevocation revokes a token by its ID, or a subject's tokens, on behalf of a policy admin.
+func CreateRevocation(ctx *gin.Context) {
+	if !requireAdmin(ctx) {
+		return
+	}
+
+	input := CreateRevocationInput{}
+	if err := ctx.ShouldBindJSON(&input); err != nil {
+		err := parseBindingError(err)
+		renderResponse(ctx, err.Status, err)
+		return
+	}
+
+	username := callerRequest(ctx).Username
+	entry, err := revocation.FromContext(ctx).Revoke(revocation.Entry{
+		Issuer:       input.Issuer,
+		TokenId:      input.TokenId,
+		Subject:      input.Subject,
+		IssuedBefore: input.IssuedBefore.UTC(),
+		Reason:       input.Reason,
+	}, username)
+	if err != nil {
+		renderRevocationError(ctx, err)
+		return
+	}
+
+	logrus.Infof("User '%s' revoked tokens (token ID '%s', subject '%s'): %s", username, entry.TokenId, entry.Subject, entry.Reason)
+	renderResponse(ctx, 201, RevocationOutput{Entry: *entry})
+}
+
+// ListRevocations returns every revocation to policy admins.
+func ListRevocations(ctx *gin.Context) {
+	if !requireAdmin(ctx) {
+		return
+	}
+
+	entries, err := revocation.FromContext(ctx).List()
+	if err != nil {
+		renderRevocationError(ctx, err)
+		return
+	}
+
+	renderResponse(ctx, 200, ListRevocationsOutput{Revocations: entries})
+}
+
+// DeleteRevocation lifts a revocation on behalf of a policy admin.
+func DeleteRevocation(ctx *gin.Context) {
+	if !requireAdmin(ctx) {
+		return
+	}
+
+	if err := revocation.FromContext(ctx).Delete(ctx.Param("id")); err != nil {
+		renderRevocationError(ctx, err)
+		return
+	}
+
+	logrus.Infof("User '%s' deleted revocation '%s'", callerRequest(ctx).Username, ctx.Param("id"))
+	ctx.Status(204)
+}
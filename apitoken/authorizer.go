@@ -0,0 +1,29 @@
+package apitoken
+
+import "github.com/hunoz/maroon-api/authorization"
+
+// Authorizer denies API tokens anything outside the scope they were issued with, before the
+// policy is consulted.
+type Authorizer struct {
+	authorization.Authorizer
+}
+
+func NewAuthorizer(authorizer authorization.Authorizer) *Authorizer {
+	return &Authorizer{Authorizer: authorizer}
+}
+
+func (a *Authorizer) Authorize(req authorization.Request) authorization.Decision {
+	if req.Issuer != IssuerName {
+		return a.Authorizer.Authorize(req)
+	}
+
+	scope, err := scopeFromClaims(req.Claims)
+	if err != nil {
+		return authorization.Decision{Reason: "API token has no valid scope"}
+	}
+	if reason := scope.check(req); reason != "" {
+		return authorization.Decision{Reason: reason}
+	}
+
+	return a.Authorizer.Authorize(req)
+}
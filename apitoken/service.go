@@ -0,0 +1,164 @@
+package apitoken
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/hunoz/maroon-api/authentication"
+	"github.com/hunoz/maroon-api/signing"
+	"github.com/hunoz/maroon-api/store"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultMaxDuration is how long an API token may last when Config.MaxDuration is not set.
+const DefaultMaxDuration = 90 * 24 * time.Hour
+
+// Config ...
+type Config struct {
+	Signer      *signing.Signer
+	MaxDuration time.Duration
+	Storage     Storage
+}
+
+// Service issues, lists and revokes API tokens.
+type Service struct {
+	signer      *signing.Signer
+	maxDuration time.Duration
+	storage     Storage
+	now         func() time.Time
+}
+
+func NewService(config *Config) *Service {
+	s := &Service{
+		signer:      config.Signer,
+		maxDuration: config.MaxDuration,
+		storage:     config.Storage,
+		now:         time.Now,
+	}
+	if s.maxDuration == 0 {
+		s.maxDuration = DefaultMaxDuration
+	}
+	if s.storage == nil {
+		s.storage = store.NewMemory[Token]()
+	}
+	return s
+}
+
+// MaxDuration returns how long tokens may last at most.
+func (s *Service) MaxDuration() time.Duration {
+	return s.maxDuration
+}
+
+// IssuerConfig trusts the service's API tokens.
+func (s *Service) IssuerConfig() (authentication.IssuerConfig, error) {
+	config, err := s.signer.IssuerConfig(IssuerName, []string{Audience})
+	if err != nil {
+		return config, err
+	}
+	config.TokenUses = []string{TokenUse}
+	return config, nil
+}
+
+// Issue signs a new token for name, limited to scope, and records its hash. The token itself
+// is only ever returned here.
+func (s *Service) Issue(name, description string, scope Scope, duration time.Duration, createdBy string) (*Token, string, error) {
+	if duration <= 0 || duration > s.maxDuration {
+		return nil, "", ErrInvalidDuration
+	}
+	if len(scope.AccountIds) == 0 {
+		return nil, "", ErrInvalidScope
+	}
+
+	id, err := store.NewId()
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := s.now().UTC()
+	token := &Token{
+		Id:          id,
+		Name:        name,
+		Description: description,
+		Scope:       scope,
+		CreatedBy:   createdBy,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(duration),
+	}
+
+	signed, err := s.signer.Sign(jwt.MapClaims{
+		"sub":       name,
+		"jti":       id,
+		"aud":       Audience,
+		"token_use": TokenUse,
+		"exp":       token.ExpiresAt.Unix(),
+		ScopeClaim:  scope,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	token.TokenHash = hash(signed)
+
+	if err = s.storage.Put(token); err != nil {
+		return nil, "", err
+	}
+
+	return token, signed, nil
+}
+
+// Get returns a single token's record.
+func (s *Service) Get(id string) (*Token, error) {
+	return s.storage.Get(id)
+}
+
+// List returns every token's record.
+func (s *Service) List() ([]*Token, error) {
+	return s.storage.List()
+}
+
+// Revoke stops a token from being accepted before it expires.
+func (s *Service) Revoke(id, revokedBy string) (*Token, error) {
+	token, err := s.storage.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if token.Revoked {
+		return nil, ErrAlreadyRevoked
+	}
+
+	token.Revoked = true
+	token.RevokedBy = revokedBy
+	token.RevokedAt = s.now().UTC()
+	if err = s.storage.Put(token); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// IsRevoked reports whether a token signed by the service has been revoked. Its signature and
+// expiry are already checked, so a token without a record, such as one issued by an instance
+// that doesn't share storage with this one, is accepted. Tokens are treated as revoked if the
+// records cannot be read. Tokens from other issuers are left to them.
+func (s *Service) IsRevoked(issuer, tokenId, subject string, issuedAt time.Time) bool {
+	if issuer != s.signer.Issuer() {
+		return false
+	}
+
+	token, err := s.storage.Get(tokenId)
+	if errors.Is(err, store.ErrNotFound) {
+		return false
+	}
+	if err != nil {
+		logrus.Errorf("Error reading API token '%s' for '%s', rejecting it: %s", tokenId, subject, err.Error())
+		return true
+	}
+	return !token.IsActive(s.now())
+}
+
+func hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,6 @@
+package apitoken
+
+import "github.com/hunoz/maroon-api/store"
+
+// Storage persists API tokens.
+type Storage = store.Store[Token]
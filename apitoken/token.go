@@ -0,0 +1,95 @@
+package apitoken
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hunoz/maroon-api/authorization"
+	"github.com/hunoz/maroon-api/store"
+)
+
+const (
+	// IssuerName identifies API tokens in policies, as principals.issuers.
+	IssuerName = "maroon"
+	// Audience is the aud claim of API tokens.
+	Audience = "maroon-api"
+	// TokenUse is the token_use claim of API tokens.
+	TokenUse = "api"
+	// ScopeClaim holds the token's Scope.
+	ScopeClaim = "maroon:scope"
+)
+
+var (
+	ErrNotFound        = store.ErrNotFound
+	ErrAlreadyRevoked  = errors.New("API token has already been revoked")
+	ErrInvalidDuration = errors.New("requested API token duration is not allowed")
+	ErrInvalidScope    = errors.New("API token scope must allow at least one account")
+)
+
+// Scope limits what an API token may be used for, on top of what the policy allows its name.
+type Scope struct {
+	AccountIds      []string `json:"accountIds"`
+	RoleArnPatterns []string `json:"roleArnPatterns"`
+	AccessTypes     []string `json:"accessTypes"`
+}
+
+// check returns why the scope does not cover the request, or an empty string if it does.
+func (s *Scope) check(req authorization.Request) string {
+	if req.AccountId != "" && !authorization.MatchesAny(s.AccountIds, req.AccountId) {
+		return fmt.Sprintf("API token is not scoped to account '%s'", req.AccountId)
+	}
+	if req.RoleArn != "" && !authorization.MatchesAny(s.RoleArnPatterns, req.RoleArn) {
+		return fmt.Sprintf("API token is not scoped to role '%s'", req.RoleArn)
+	}
+	if req.AccessType != "" && !authorization.MatchesAny(s.AccessTypes, req.AccessType) {
+		return fmt.Sprintf("API token is not scoped to access type '%s'", req.AccessType)
+	}
+	return ""
+}
+
+// scopeFromClaims reads the scope a token was issued with.
+func scopeFromClaims(claims map[string]interface{}) (*Scope, error) {
+	raw, exists := claims[ScopeClaim]
+	if !exists {
+		return nil, ErrInvalidScope
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	scope := &Scope{}
+	if err = json.Unmarshal(data, scope); err != nil {
+		return nil, err
+	}
+	return scope, nil
+}
+
+// Token is the record of an issued API token. Only a hash of the token itself is kept.
+type Token struct {
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	TokenHash   string    `json:"tokenHash"`
+	Scope       Scope     `json:"scope"`
+	CreatedBy   string    `json:"createdBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	Revoked     bool      `json:"revoked"`
+	RevokedBy   string    `json:"revokedBy,omitempty"`
+	RevokedAt   time.Time `json:"revokedAt"`
+}
+
+func (t Token) StoreKey() string {
+	return t.Id
+}
+
+func (t Token) StoreOrder() time.Time {
+	return t.CreatedAt
+}
+
+// IsActive reports whether the token has neither expired nor been revoked.
+func (t *Token) IsActive(now time.Time) bool {
+	return !t.Revoked && now.Before(t.ExpiresAt)
+}
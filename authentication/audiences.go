@@ -0,0 +1,40 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/pkg/errors"
+)
+
+// LoadAudiences reads the accepted audiences from a Secrets Manager secret in the
+// format ["<AUDIENCE>", "<AUDIENCE>"].
+func LoadAudiences(secretId string) ([]string, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating config")
+	}
+	client := secretsmanager.NewFromConfig(cfg)
+
+	output, err := client.GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretId),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting audiences")
+	}
+
+	secret := aws.ToString(output.SecretString)
+	if secret == "" {
+		return nil, errors.Errorf("Audiences secret '%s' has no secret string", secretId)
+	}
+
+	var audiences []string
+	if err = json.Unmarshal([]byte(secret), &audiences); err != nil {
+		return nil, errors.Wrap(err, "Error parsing audiences")
+	}
+
+	return audiences, nil
+}
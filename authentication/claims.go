@@ -0,0 +1,71 @@
+package authentication
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var (
+	ErrTokenExpired         = errors.New("token is expired")
+	ErrTokenNotValidYet     = errors.New("token is not valid yet")
+	ErrTokenUsedEarly       = errors.New("token used before issued")
+	ErrInvalidIssuer        = errors.New("token issuer is not trusted")
+	ErrInvalidAudience      = errors.New("token audience is not accepted")
+	ErrInvalidTokenUse      = errors.New("token use is not accepted")
+	ErrNotReady             = errors.New("token issuer keys are not available yet")
+	ErrKeyNotFound          = errors.New("token signing key is not known")
+	ErrUnsupportedAlgorithm = errors.New("token signing algorithm is not accepted")
+	ErrTokenRevoked         = errors.New("token has been revoked")
+	ErrSessionsDisabled     = errors.New("no session key is configured")
+	ErrInvalidSession       = errors.New("session cookie is not valid")
+)
+
+// validateClaims checks the registered claims that the signature check does not cover,
+// allowing for clockSkew of drift between us and the issuer.
+func (i *Issuer) validateClaims(claims jwt.MapClaims) error {
+	now := time.Now().Unix()
+	skew := int64(i.clockSkew.Seconds())
+
+	if !claims.VerifyExpiresAt(now-skew, true) {
+		return ErrTokenExpired
+	}
+	if !claims.VerifyNotBefore(now+skew, false) {
+		return ErrTokenNotValidYet
+	}
+	if !claims.VerifyIssuedAt(now+skew, false) {
+		return ErrTokenUsedEarly
+	}
+	if !claims.VerifyIssuer(i.issuer, true) {
+		return ErrInvalidIssuer
+	}
+	if len(i.audiences) > 0 && !verifyAudience(claims, i.audiences) {
+		return ErrInvalidAudience
+	}
+	if len(i.tokenUses) > 0 && !contains(i.tokenUses, claims["token_use"]) {
+		return ErrInvalidTokenUse
+	}
+
+	return nil
+}
+
+// verifyAudience accepts the token if its aud claim (ID tokens) or client_id claim (Cognito
+// access tokens) is one of the audiences.
+func verifyAudience(claims jwt.MapClaims, audiences []string) bool {
+	for _, audience := range audiences {
+		if claims.VerifyAudience(audience, true) {
+			return true
+		}
+	}
+	return contains(audiences, claims["client_id"])
+}
+
+func contains(values []string, value interface{}) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package authentication
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// CognitoIssuer returns the issuer URL of a Cognito user pool.
+func CognitoIssuer(region string, userPoolID string) string {
+	return fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s", region, userPoolID)
+}
+
+// DiscoveryDocument is the subset of an OpenID Provider's configuration that we use.
+// See https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata.
+type DiscoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	JWKSURI               string `json:"jwks_uri"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// Discover fetches the issuer's /.well-known/openid-configuration document.
+func Discover(issuer string) (*DiscoveryDocument, error) {
+	req, err := http.NewRequest("GET", strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("Accept", "application/json")
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d fetching discovery document for '%s'", resp.StatusCode, issuer)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	document := new(DiscoveryDocument)
+	if err = json.Unmarshal(body, document); err != nil {
+		return nil, err
+	}
+	if document.Issuer != issuer {
+		return nil, fmt.Errorf("discovery document issuer '%s' does not match '%s'", document.Issuer, issuer)
+	}
+	if document.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery document for '%s' has no jwks_uri", issuer)
+	}
+
+	return document, nil
+}
@@ -0,0 +1,109 @@
+package authentication
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Error is an authentication failure, described the way RFC 6750 expects a bearer token
+// failure to be reported. Reason is a machine-readable cause for clients.
+type Error struct {
+	Status      int
+	Code        string
+	Reason      string
+	Description string
+}
+
+func (e *Error) Error() string {
+	return e.Description
+}
+
+// Challenge is the WWW-Authenticate header value for the failure, or an empty string if the
+// failure is not something the client can fix by authenticating differently.
+func (e *Error) Challenge() string {
+	switch {
+	case e.Status != http.StatusUnauthorized && e.Status != http.StatusBadRequest:
+		return ""
+	case e.Code == "":
+		// A request without credentials gets a challenge without an error code
+		return "Bearer"
+	default:
+		return fmt.Sprintf(`Bearer error="%s", error_description=%q`, e.Code, e.Description)
+	}
+}
+
+func missingTokenError() *Error {
+	return &Error{
+		Status:      http.StatusUnauthorized,
+		Reason:      "missing_token",
+		Description: "no bearer token was provided",
+	}
+}
+
+func invalidRequestError(description string) *Error {
+	return &Error{
+		Status:      http.StatusBadRequest,
+		Code:        "invalid_request",
+		Reason:      "malformed_authorization_header",
+		Description: description,
+	}
+}
+
+// newError describes an error from ParseJWT.
+func newError(err error) *Error {
+	if errors.Is(err, ErrNotReady) {
+		return &Error{
+			Status:      http.StatusServiceUnavailable,
+			Reason:      "issuer_unavailable",
+			Description: err.Error(),
+		}
+	}
+
+	return &Error{
+		Status:      http.StatusUnauthorized,
+		Code:        "invalid_token",
+		Reason:      invalidTokenReason(err),
+		Description: err.Error(),
+	}
+}
+
+func invalidTokenReason(err error) string {
+	var validationErr *jwt.ValidationError
+	if errors.As(err, &validationErr) {
+		switch {
+		case validationErr.Errors&jwt.ValidationErrorMalformed != 0:
+			return "malformed"
+		case validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+			return "bad_signature"
+		case validationErr.Inner != nil:
+			// Errors from the key lookup are wrapped without Unwrap support
+			return invalidTokenReason(validationErr.Inner)
+		}
+	}
+
+	switch {
+	case errors.Is(err, ErrTokenExpired):
+		return "expired"
+	case errors.Is(err, ErrTokenNotValidYet):
+		return "not_yet_valid"
+	case errors.Is(err, ErrTokenUsedEarly):
+		return "used_before_issued"
+	case errors.Is(err, ErrInvalidIssuer):
+		return "unknown_issuer"
+	case errors.Is(err, ErrInvalidAudience):
+		return "invalid_audience"
+	case errors.Is(err, ErrInvalidTokenUse):
+		return "invalid_token_use"
+	case errors.Is(err, ErrKeyNotFound):
+		return "unknown_key"
+	case errors.Is(err, ErrUnsupportedAlgorithm):
+		return "unsupported_algorithm"
+	case errors.Is(err, ErrTokenRevoked):
+		return "revoked"
+	default:
+		return "invalid_token"
+	}
+}
@@ -0,0 +1,109 @@
+package authentication
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/hunoz/maroon-api/ginctx"
+)
+
+var (
+	identityContextKey = ginctx.Key[*Identity]("identity")
+	claimsContextKey   = ginctx.Key[jwt.MapClaims]("claims")
+)
+
+// PrincipalType tells people apart from automation such as CI pipelines.
+type PrincipalType string
+
+const (
+	PrincipalTypeUser    PrincipalType = "user"
+	PrincipalTypeMachine PrincipalType = "machine"
+)
+
+// Identity is who the caller is, mapped from their token's claims.
+type Identity struct {
+	Type     PrincipalType
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+	Issuer   string
+}
+
+// ClaimMapping names the claims that hold the user's identity. Empty fields use the Cognito claims.
+type ClaimMapping struct {
+	Subject  string `json:"subject"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Groups   string `json:"groups"`
+}
+
+func (m *ClaimMapping) setDefaults() {
+	if m.Subject == "" {
+		m.Subject = "sub"
+	}
+	if m.Username == "" {
+		m.Username = "cognito:username"
+	}
+	if m.Email == "" {
+		m.Email = "email"
+	}
+	if m.Groups == "" {
+		m.Groups = "cognito:groups"
+	}
+}
+
+// identity maps the claims to an Identity from the issuer.
+func (m *ClaimMapping) identity(claims jwt.MapClaims, principalType PrincipalType, issuer string) *Identity {
+	return &Identity{
+		Type:     principalType,
+		Subject:  stringClaim(claims, m.Subject),
+		Username: stringClaim(claims, m.Username),
+		Email:    stringClaim(claims, m.Email),
+		Groups:   stringsClaim(claims, m.Groups),
+		Issuer:   issuer,
+	}
+}
+
+func stringClaim(claims jwt.MapClaims, name string) string {
+	if value, exists := claims[name]; exists && value != nil {
+		return fmt.Sprint(value)
+	}
+	return ""
+}
+
+// stringsClaim reads a list claim, accepting a space-separated string as some IdPs send.
+func stringsClaim(claims jwt.MapClaims, name string) []string {
+	switch value := claims[name].(type) {
+	case []string:
+		return value
+	case []interface{}:
+		values := make([]string, len(value))
+		for i, v := range value {
+			values[i] = fmt.Sprint(v)
+		}
+		return values
+	case string:
+		return strings.Fields(value)
+	default:
+		return []string{}
+	}
+}
+
+// GetIdentity returns the caller's identity. It is empty if the request was not authenticated.
+func GetIdentity(ctx *gin.Context) *Identity {
+	if identity, exists := identityContextKey.Get(ctx); exists {
+		return identity
+	}
+	return &Identity{Groups: []string{}}
+}
+
+// GetClaims returns all of the claims of the caller's token.
+func GetClaims(ctx *gin.Context) jwt.MapClaims {
+	if claims, exists := claimsContextKey.Get(ctx); exists {
+		return claims
+	}
+	return jwt.MapClaims{}
+}
@@ -0,0 +1,244 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/sirupsen/logrus"
+)
+
+// Presets for workload identity issuers. Kubernetes issuers differ per cluster, so Issuer
+// must still be set for them.
+const (
+	PresetGitHubActions = "github-actions"
+	PresetKubernetes    = "kubernetes"
+)
+
+const (
+	// minInitInterval is the shortest time between attempts to initialize an issuer.
+	minInitInterval = 5 * time.Second
+	// maxInitBackoff is the longest time between background attempts to initialize an issuer.
+	maxInitBackoff = 5 * time.Minute
+)
+
+// GitHubActionsIssuer is the issuer of GitHub Actions OIDC tokens.
+const GitHubActionsIssuer = "https://token.actions.githubusercontent.com"
+
+// IssuerConfig ...
+type IssuerConfig struct {
+	// Name identifies the issuer's users in policies and logs. It defaults to the issuer URL.
+	Name string `json:"name"`
+	// Preset configures a workload identity issuer, either "github-actions" or "kubernetes".
+	Preset string `json:"preset"`
+	// PrincipalType is whether the issuer's tokens are for users or machines. It defaults to users.
+	PrincipalType PrincipalType `json:"principalType"`
+	// Issuer is the OIDC issuer URL. Its signing keys are found through the issuer's
+	// discovery document, and tokens must carry it as their iss claim.
+	Issuer string `json:"issuer"`
+	// JWKSURL overrides the jwks_uri from the discovery document.
+	JWKSURL string `json:"jwksUrl"`
+	// JWKSFile and JWKS load the key set from a local file or inline JSON instead of a URL,
+	// for air-gapped deployments, local development and tests.
+	JWKSFile string          `json:"jwksFile"`
+	JWKS     json.RawMessage `json:"jwks"`
+	// CognitoRegion and CognitoUserPoolID are a preset for the issuer of a Cognito user pool,
+	// used when Issuer is not set.
+	CognitoRegion     string `json:"cognitoRegion"`
+	CognitoUserPoolID string `json:"cognitoUserPoolId"`
+	// Audiences are the accepted aud or client_id claims. Any audience is accepted if empty,
+	// except for presets, which must set them.
+	Audiences []string `json:"audiences"`
+	// Algorithms are the accepted signing algorithms, such as RS256, PS256, ES256 or EdDSA.
+	// Only RS256 is accepted if empty.
+	Algorithms []string `json:"algorithms"`
+	// TokenUses are the accepted token_use claims, such as "id" or "access". Any is accepted if empty.
+	TokenUses []string `json:"tokenUses"`
+	// ClaimMapping names the claims that hold the user's identity.
+	ClaimMapping ClaimMapping `json:"claimMapping"`
+}
+
+// Issuer is a trusted token issuer with its own key set and claim rules.
+type Issuer struct {
+	name          string
+	principalType PrincipalType
+	issuer        string
+	keys          *keyStore
+	audiences     []string
+	algorithms    []string
+	tokenUses     []string
+	claimMapping  ClaimMapping
+	clockSkew     time.Duration
+
+	initMu          sync.Mutex
+	lastInitAttempt time.Time
+}
+
+func newIssuer(config IssuerConfig, clockSkew time.Duration) (*Issuer, error) {
+	i := &Issuer{
+		name:          config.Name,
+		principalType: config.PrincipalType,
+		issuer:        config.Issuer,
+		audiences:     config.Audiences,
+		algorithms:    config.Algorithms,
+		tokenUses:     config.TokenUses,
+		claimMapping:  config.ClaimMapping,
+		clockSkew:     clockSkew,
+	}
+
+	switch config.Preset {
+	case "":
+	case PresetGitHubActions:
+		if i.issuer == "" {
+			i.issuer = GitHubActionsIssuer
+		}
+		i.setMachineDefaults()
+	case PresetKubernetes:
+		// Clusters have no well-known issuer, and without one this would be a Cognito issuer.
+		if i.issuer == "" {
+			return nil, fmt.Errorf("issuer with preset '%s' must set issuer", config.Preset)
+		}
+		i.setMachineDefaults()
+	default:
+		return nil, fmt.Errorf("unknown issuer preset '%s'", config.Preset)
+	}
+	// Anyone can get a token from a workload issuer, such as GitHub Actions for any repository,
+	// so its tokens must be meant for Maroon.
+	if config.Preset != "" && len(i.audiences) == 0 {
+		return nil, fmt.Errorf("issuer '%s' with preset '%s' must set audiences", i.issuer, config.Preset)
+	}
+
+	if i.issuer == "" {
+		i.issuer = CognitoIssuer(config.CognitoRegion, config.CognitoUserPoolID)
+	}
+	if i.principalType == "" {
+		i.principalType = PrincipalTypeUser
+	}
+	if i.name == "" {
+		i.name = i.issuer
+	}
+	i.claimMapping.setDefaults()
+
+	if len(i.algorithms) == 0 {
+		i.algorithms = DefaultAlgorithms
+	}
+	for _, algorithm := range i.algorithms {
+		if !contains(SupportedAlgorithms, algorithm) {
+			return nil, fmt.Errorf("unsupported signing algorithm '%s' for issuer '%s'", algorithm, i.issuer)
+		}
+	}
+
+	i.keys = newKeyStore(config.JWKSURL, config.JWKSFile, config.JWKS)
+
+	return i, nil
+}
+
+// initialize resolves the issuer's key set URL, if needed, and fetches its keys.
+func (i *Issuer) initialize() error {
+	i.initMu.Lock()
+	defer i.initMu.Unlock()
+	return i.initializeLocked()
+}
+
+// tryInitialize initializes the issuer unless that was attempted within minInitInterval,
+// so that requests arriving while the issuer is unreachable do not pile up behind it.
+func (i *Issuer) tryInitialize() {
+	i.initMu.Lock()
+	defer i.initMu.Unlock()
+	if i.Ready() || time.Since(i.lastInitAttempt) < minInitInterval {
+		return
+	}
+	if err := i.initializeLocked(); err != nil {
+		logrus.Errorf("Error initializing issuer '%s': %s", i.name, err.Error())
+	}
+}
+
+func (i *Issuer) initializeLocked() error {
+	if i.Ready() {
+		return nil
+	}
+	i.lastInitAttempt = time.Now()
+
+	if !i.keys.HasSource() {
+		document, err := Discover(i.issuer)
+		if err != nil {
+			return err
+		}
+		i.keys.SetURL(document.JWKSURI)
+	}
+
+	return i.CacheJWK()
+}
+
+// run retries initialization with backoff until it succeeds and then keeps the key set
+// fresh, until the context is done.
+func (i *Issuer) run(ctx context.Context) {
+	backoff := minInitInterval
+	for !i.Ready() {
+		if err := i.initialize(); err != nil {
+			logrus.Errorf("Error initializing issuer '%s', retrying in %s: %s", i.name, backoff, err.Error())
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > maxInitBackoff {
+				backoff = maxInitBackoff
+			}
+		}
+	}
+	i.keys.run(ctx)
+}
+
+// Ready reports whether the issuer has a key set to validate tokens with.
+func (i *Issuer) Ready() bool {
+	return i.keys.JWK() != nil
+}
+
+// setMachineDefaults identifies workload tokens by their subject, such as
+// "repo:<org>/<repo>:ref:refs/heads/main" or "system:serviceaccount:<namespace>:<name>".
+func (i *Issuer) setMachineDefaults() {
+	if i.principalType == "" {
+		i.principalType = PrincipalTypeMachine
+	}
+	if i.claimMapping.Username == "" {
+		i.claimMapping.Username = "sub"
+	}
+}
+
+// CacheJWK fetches the issuer's key set.
+func (i *Issuer) CacheJWK() error {
+	return i.keys.Refresh()
+}
+
+func (i *Issuer) Name() string {
+	return i.name
+}
+
+func (i *Issuer) PrincipalType() PrincipalType {
+	return i.principalType
+}
+
+func (i *Issuer) Issuer() string {
+	return i.issuer
+}
+
+func (i *Issuer) JWK() *JWK {
+	return i.keys.JWK()
+}
+
+func (i *Issuer) JWKURL() string {
+	return i.keys.URL()
+}
+
+func (i *Issuer) ClaimMapping() ClaimMapping {
+	return i.claimMapping
+}
+
+// Identity maps the token's claims to the caller's identity.
+func (i *Issuer) Identity(claims jwt.MapClaims) *Identity {
+	return i.claimMapping.identity(claims, i.principalType, i.name)
+}
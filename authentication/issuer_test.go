@@ -0,0 +1,63 @@
+package authentication
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewIssuerPresets(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		config     IssuerConfig
+		wantIssuer string
+		wantErr    string
+	}{
+		{
+			name:       "github-actions",
+			config:     IssuerConfig{Preset: PresetGitHubActions, Audiences: []string{"maroon"}},
+			wantIssuer: GitHubActionsIssuer,
+		},
+		{
+			name:    "github-actions without audiences",
+			config:  IssuerConfig{Preset: PresetGitHubActions},
+			wantErr: "must set audiences",
+		},
+		{
+			name:       "kubernetes",
+			config:     IssuerConfig{Preset: PresetKubernetes, Issuer: "https://cluster.example.com", Audiences: []string{"maroon"}},
+			wantIssuer: "https://cluster.example.com",
+		},
+		{
+			name:    "kubernetes without issuer",
+			config:  IssuerConfig{Preset: PresetKubernetes, Audiences: []string{"maroon"}},
+			wantErr: "must set issuer",
+		},
+		{
+			name:    "kubernetes without audiences",
+			config:  IssuerConfig{Preset: PresetKubernetes, Issuer: "https://cluster.example.com"},
+			wantErr: "must set audiences",
+		},
+		{
+			name:    "unknown preset",
+			config:  IssuerConfig{Preset: "other"},
+			wantErr: "unknown issuer preset",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			issuer, err := newIssuer(tc.config, time.Minute)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("newIssuer() error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if issuer.issuer != tc.wantIssuer || issuer.principalType != PrincipalTypeMachine {
+				t.Errorf("newIssuer() = %s %s, want %s machine issuer", issuer.issuer, issuer.principalType, tc.wantIssuer)
+			}
+		})
+	}
+}
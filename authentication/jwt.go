@@ -1,16 +1,16 @@
 package authentication
 
 import (
+	"context"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/binary"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"log"
 	"math/big"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -20,130 +20,222 @@ import (
 
 // Auth ...
 type Auth struct {
-	jwk               *JWK
-	jwkURL            string
-	cognitoRegion     string
-	cognitoUserPoolID string
+	issuers map[string]*Issuer
+	// defaultIssuer is the name of the first configured issuer of user tokens.
+	defaultIssuer string
+	tokens        *tokenCache
+	denylists     []Denylist
+	sessions      *sessionCipher
+	cancel        context.CancelFunc
 }
 
 // Config ...
 type Config struct {
-	CognitoRegion     string
-	CognitoUserPoolID string
+	// Issuers are the trusted token issuers. Tokens are matched to one by their iss claim.
+	Issuers []IssuerConfig
+	// ClockSkew is how far exp, nbf and iat may be off from our clock.
+	ClockSkew time.Duration
+	// TokenCacheSize is how many validated tokens to remember until they expire. Zero disables the cache.
+	TokenCacheSize int
+	// Denylists are checked for every token, including those in the token cache.
+	Denylists []Denylist
+	// SessionKey, if set, is the SessionKeySize-byte key session cookies are encrypted with.
+	// Without it only the Authorization header is accepted.
+	SessionKey []byte
+}
+
+// Denylist reports whether an otherwise valid token has been revoked.
+type Denylist interface {
+	IsRevoked(issuer, tokenId, subject string, issuedAt time.Time) bool
 }
 
 // JWK ...
 type JWK struct {
-	Keys []struct {
-		Alg string `json:"alg"`
-		E   string `json:"e"`
-		Kid string `json:"kid"`
-		Kty string `json:"kty"`
-		N   string `json:"n"`
-	} `json:"keys"`
+	Keys []JSONWebKey `json:"keys"`
 }
 
-func NewAuth(config *Config) *Auth {
-	a := &Auth{
-		cognitoRegion:     config.CognitoRegion,
-		cognitoUserPoolID: config.CognitoUserPoolID,
+// JSONWebKey ...
+type JSONWebKey struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	// RSA keys
+	E string `json:"e"`
+	N string `json:"n"`
+	// EC and OKP keys
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// NewAuth sets up the trusted issuers and tries once to fetch their keys. Issuers whose keys
+// cannot be fetched yet are retried in the background, and their tokens are rejected with
+// ErrNotReady until then. Background work stops when ctx is done or Close is called. Only an
+// invalid configuration is an error.
+func NewAuth(ctx context.Context, config *Config) (*Auth, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	a := &Auth{issuers: map[string]*Issuer{}, denylists: config.Denylists, cancel: cancel}
+	if config.TokenCacheSize > 0 {
+		a.tokens = newTokenCache(config.TokenCacheSize)
+	}
+	if len(config.SessionKey) > 0 {
+		sessions, err := newSessionCipher(config.SessionKey)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		a.sessions = sessions
 	}
 
-	a.jwkURL = fmt.Sprintf("https://cognito-idp.%s.amazonaws.com/%s/.well-known/jwks.json", a.cognitoRegion, a.cognitoUserPoolID)
-	err := a.CacheJWK()
-	if err != nil {
-		log.Fatal(err)
+	for _, issuerConfig := range config.Issuers {
+		issuer, err := newIssuer(issuerConfig, config.ClockSkew)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		a.issuers[issuer.issuer] = issuer
+		if a.defaultIssuer == "" && issuerConfig.Preset == "" && issuer.principalType == PrincipalTypeUser {
+			a.defaultIssuer = issuer.name
+		}
 	}
 
-	// This will refresh the JWKS every 30 minutes. See https://stackoverflow.com/questions/16466320/is-there-a-way-to-do-repetitive-tasks-at-intervals.
-	ticker := time.NewTicker(30 * time.Minute)
-	quit := make(chan struct{})
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				logrus.Info("Refreshing JWKS")
-				a.CacheJWK()
-			case <-quit:
-				ticker.Stop()
-				return
-			}
+	for _, issuer := range a.issuers {
+		if err := issuer.initialize(); err != nil {
+			logrus.Errorf("Error initializing issuer '%s', retrying in the background: %s", issuer.name, err.Error())
 		}
-	}()
+		go issuer.run(ctx)
+	}
 
-	return a
+	return a, nil
 }
 
+// Close stops refreshing the issuers' keys.
+func (a *Auth) Close() {
+	a.cancel()
+}
+
+// Ready reports whether every issuer has keys to validate tokens with.
+func (a *Auth) Ready() bool {
+	for _, issuer := range a.issuers {
+		if !issuer.Ready() {
+			return false
+		}
+	}
+	return true
+}
+
+// CacheJWK refreshes the key sets of every issuer.
 func (a *Auth) CacheJWK() error {
-	req, err := http.NewRequest("GET", a.jwkURL, nil)
-	if err != nil {
-		return err
+	var lastErr error
+	for _, issuer := range a.issuers {
+		if err := issuer.CacheJWK(); err != nil {
+			logrus.Errorf("Error refreshing JWKS for issuer '%s': %s", issuer.name, err.Error())
+			lastErr = err
+		}
 	}
+	return lastErr
+}
+
+// DefaultIssuer returns the name of the first configured issuer of user tokens, whose users
+// policies and approvers refer to unless they name another issuer.
+func (a *Auth) DefaultIssuer() string {
+	return a.defaultIssuer
+}
+
+// Issuer returns the trusted issuer with the given iss claim, or nil.
+func (a *Auth) Issuer(iss string) *Issuer {
+	return a.issuers[iss]
+}
 
-	req.Header.Add("Accept", "application/json")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// ParseJWT validates a token and returns its claims.
+func (a *Auth) ParseJWT(tokenString string) (jwt.MapClaims, error) {
+	claims, err := a.parseJWT(tokenString)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	if a.isRevoked(claims) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
 
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+func (a *Auth) isRevoked(claims jwt.MapClaims) bool {
+	iss, _ := claims["iss"].(string)
+	jti, _ := claims["jti"].(string)
+	sub, _ := claims["sub"].(string)
+	var issuedAt time.Time
+	if iat, ok := claims["iat"].(float64); ok {
+		issuedAt = time.Unix(int64(iat), 0)
 	}
+	for _, denylist := range a.denylists {
+		if denylist.IsRevoked(iss, jti, sub, issuedAt) {
+			return true
+		}
+	}
+	return false
+}
 
-	jwk := new(JWK)
-	err = json.Unmarshal(body, jwk)
-	if err != nil {
-		return err
+func (a *Auth) parseJWT(tokenString string) (jwt.MapClaims, error) {
+	if a.tokens != nil {
+		if claims, found := a.tokens.Get(tokenString); found {
+			return claims, nil
+		}
 	}
 
-	a.jwk = jwk
-	return nil
-}
+	unverifiedClaims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(tokenString, unverifiedClaims); err != nil {
+		return nil, err
+	}
+	iss, _ := unverifiedClaims["iss"].(string)
+	issuer := a.Issuer(iss)
+	if issuer == nil {
+		return nil, ErrInvalidIssuer
+	}
+	if !issuer.Ready() {
+		issuer.tryInitialize()
+		if !issuer.Ready() {
+			return nil, ErrNotReady
+		}
+	}
 
-func (a *Auth) ParseJWT(tokenString string) (jwt.MapClaims, error) {
 	claims := jwt.MapClaims{}
-	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		index := 0
-		found := false
-		for i, v := range a.jwk.Keys {
-			if v.Kid == token.Header["kid"] {
-				index = i
-				found = true
-			}
+	// Registered claims are validated below so that clock skew can be allowed for. Signing
+	// methods are checked in the key lookup rather than with ValidMethods so that a rejected
+	// algorithm can be told apart from a bad signature.
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	_, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if !contains(issuer.algorithms, token.Method.Alg()) {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedAlgorithm, token.Header["alg"])
 		}
+		kid, _ := token.Header["kid"].(string)
+		key, found := issuer.keys.Key(kid)
 		if !found {
-			return nil, errors.New("key not found")
+			return nil, fmt.Errorf("%w: '%s'", ErrKeyNotFound, kid)
 		}
-		if token.Method.Alg() != "RS256" {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		if !keyAllowsAlgorithm(key, token.Method.Alg()) {
+			return nil, fmt.Errorf("%w for key '%s': %v", ErrUnsupportedAlgorithm, kid, token.Header["alg"])
 		}
-		key := convertKey(a.jwk.Keys[index].E, a.jwk.Keys[index].N)
 
-		return key, nil
+		return key.Key, nil
 	})
 	if err != nil {
 		return nil, err
 	}
+	if err = issuer.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	if a.tokens != nil {
+		a.tokens.Add(tokenString, claims)
+	}
 
 	return claims, nil
 }
 
-func (a *Auth) JWK() *JWK {
-	return a.jwk
-}
-
-func (a *Auth) JWKURL() string {
-	return a.jwkURL
-}
-
 // https://gist.github.com/MathieuMailhos/361f24316d2de29e8d41e808e0071b13
-func convertKey(rawE, rawN string) *rsa.PublicKey {
+func convertKey(rawE, rawN string) (*rsa.PublicKey, error) {
 	decodedE, err := base64.RawURLEncoding.DecodeString(rawE)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	if len(decodedE) < 4 {
 		ndata := make([]byte, 4)
@@ -156,37 +248,100 @@ func convertKey(rawE, rawN string) *rsa.PublicKey {
 	}
 	decodedN, err := base64.RawURLEncoding.DecodeString(rawN)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 	pubKey.N.SetBytes(decodedN)
-	return pubKey
+	return pubKey, nil
+}
+
+// bearerToken extracts the token from an Authorization header, which may carry either the
+// RFC 6750 Bearer scheme or the raw token on its own.
+func bearerToken(header string) (string, *Error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return "", missingTokenError()
+	}
+
+	scheme, token, found := strings.Cut(header, " ")
+	if !found {
+		return header, nil
+	}
+	if !strings.EqualFold(scheme, "Bearer") {
+		return "", invalidRequestError(fmt.Sprintf("unsupported authorization scheme '%s'", scheme))
+	}
+	token = strings.TrimSpace(token)
+	if token == "" || strings.Contains(token, " ") {
+		return "", invalidRequestError("malformed bearer token")
+	}
+
+	return token, nil
+}
+
+// abort stops the request with err. The error is attached to the context for the API layer to
+// render in its response envelope.
+func abort(ctx *gin.Context, err *Error) {
+	if challenge := err.Challenge(); challenge != "" {
+		ctx.Header("WWW-Authenticate", challenge)
+	}
+	if err.Status == http.StatusServiceUnavailable {
+		ctx.Header("Retry-After", strconv.Itoa(int(minInitInterval.Seconds())))
+	}
+	_ = ctx.AbortWithError(err.Status, err)
+}
+
+// requestToken returns the token from the Authorization header or, without one, from the
+// session cookie. Browsers send the cookie with requests other sites make, so it is only
+// accepted on requests that don't change anything.
+func (a *Auth) requestToken(ctx *gin.Context) (string, *Error) {
+	header := ctx.GetHeader("Authorization")
+	cookie, err := ctx.Cookie(SessionCookieName)
+	if strings.TrimSpace(header) != "" || a.sessions == nil || err != nil {
+		return bearerToken(header)
+	}
+	if method := ctx.Request.Method; method != http.MethodGet && method != http.MethodHead {
+		return "", &Error{
+			Status:      http.StatusUnauthorized,
+			Reason:      "session_not_allowed",
+			Description: fmt.Sprintf("session cookies are not accepted on %s requests, send the token in the Authorization header", method),
+		}
+	}
+
+	token, err := a.sessions.open(cookie)
+	if err != nil {
+		return "", &Error{
+			Status:      http.StatusUnauthorized,
+			Code:        "invalid_token",
+			Reason:      "invalid_session",
+			Description: err.Error(),
+		}
+	}
+	return token, nil
 }
 
 func JWTMiddleware(auth Auth) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		tokenHeader := ctx.GetHeader("Authorization")
-		if tokenHeader == "" {
-			ctx.AbortWithStatus(401)
+		tokenString, authErr := auth.requestToken(ctx)
+		if authErr != nil {
+			abort(ctx, authErr)
+			return
 		}
 
-		claims, err := auth.ParseJWT(tokenHeader)
-		if err != nil {
-			logrus.Errorf("Invalid token")
-			ctx.AbortWithStatus(401)
-		} else {
-			for key, val := range claims {
-				if key == "cognito:username" {
-					ctx.Set("username", val)
-				} else if key == "cognito:groups" {
-					ctx.Set("groups", val)
-				} else {
-					ctx.Set(key, val)
-				}
-			}
-			ctx.Set("token", tokenHeader)
-			username, _ := ctx.Get("username")
-			logrus.Infof("Validated token for user '%v'", username)
-			ctx.Next()
+		claims, err := auth.ParseJWT(tokenString)
+		if errors.Is(err, ErrNotReady) {
+			logrus.Errorf("Rejecting token while its issuer has no keys: %s", err.Error())
+			abort(ctx, newError(err))
+			return
+		} else if err != nil {
+			logrus.Errorf("Invalid token: %s", err.Error())
+			abort(ctx, newError(err))
+			return
 		}
+
+		identity := auth.Issuer(claims["iss"].(string)).Identity(claims)
+		identityContextKey.Set(ctx, identity)
+		claimsContextKey.Set(ctx, claims)
+		ctx.Set("token", tokenString)
+		logrus.Infof("Validated token for user '%s' from issuer '%s'", identity.Username, identity.Issuer)
+		ctx.Next()
 	}
 }
@@ -0,0 +1,255 @@
+package authentication
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const testIssuer = "https://issuer.example.com"
+
+// testKey is an RSA key in the test key set, with the ID tokens name it by.
+type testKey struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+func newTestKeys(t testing.TB, count int) []testKey {
+	t.Helper()
+	keys := make([]testKey, count)
+	for i := range keys {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = testKey{kid: fmt.Sprintf("key-%d", i), key: key}
+	}
+	return keys
+}
+
+func testJWKS(t testing.TB, keys []testKey) json.RawMessage {
+	t.Helper()
+	jwk := JWK{}
+	for _, k := range keys {
+		jwk.Keys = append(jwk.Keys, JSONWebKey{
+			Alg: "RS256",
+			Kid: k.kid,
+			Kty: "RSA",
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.key.E)).Bytes()),
+			N:   base64.RawURLEncoding.EncodeToString(k.key.N.Bytes()),
+		})
+	}
+	raw, err := json.Marshal(jwk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func newTestAuth(t testing.TB, config IssuerConfig, tokenCacheSize int) *Auth {
+	t.Helper()
+	if config.Issuer == "" {
+		config.Issuer = testIssuer
+	}
+	auth, err := NewAuth(context.Background(), &Config{
+		Issuers:        []IssuerConfig{config},
+		ClockSkew:      time.Minute,
+		TokenCacheSize: tokenCacheSize,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(auth.Close)
+	if !auth.Ready() {
+		t.Fatal("issuer is not ready")
+	}
+	return auth
+}
+
+func testClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":       testIssuer,
+		"sub":       "subject",
+		"aud":       "audience",
+		"username":  "user",
+		"token_use": "id",
+		"iat":       now.Unix(),
+		"exp":       now.Add(time.Hour).Unix(),
+	}
+}
+
+func signTestToken(t testing.TB, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenString
+}
+
+func BenchmarkParseJWT(b *testing.B) {
+	keys := newTestKeys(b, 4)
+	tokenString := signTestToken(b, jwt.SigningMethodRS256, keys[3].kid, keys[3].key, testClaims())
+
+	for _, bm := range []struct {
+		name           string
+		tokenCacheSize int
+	}{
+		{name: "without cache", tokenCacheSize: 0},
+		{name: "with cache", tokenCacheSize: 100},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			auth := newTestAuth(b, IssuerConfig{JWKS: testJWKS(b, keys)}, bm.tokenCacheSize)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := auth.ParseJWT(tokenString); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestParseJWTClaims(t *testing.T) {
+	keys := newTestKeys(t, 1)
+	auth := newTestAuth(t, IssuerConfig{
+		JWKS:      testJWKS(t, keys),
+		Audiences: []string{"audience"},
+		TokenUses: []string{"id"},
+	}, 0)
+	now := time.Now()
+
+	for _, tc := range []struct {
+		name       string
+		edit       func(jwt.MapClaims)
+		wantReason string
+	}{
+		{name: "valid", edit: func(c jwt.MapClaims) {}},
+		{name: "expired within clock skew", edit: func(c jwt.MapClaims) { c["exp"] = now.Add(-30 * time.Second).Unix() }},
+		{name: "expired", edit: func(c jwt.MapClaims) { c["exp"] = now.Add(-2 * time.Minute).Unix() }, wantReason: "expired"},
+		{name: "without exp", edit: func(c jwt.MapClaims) { delete(c, "exp") }, wantReason: "expired"},
+		{name: "not valid yet within clock skew", edit: func(c jwt.MapClaims) { c["nbf"] = now.Add(30 * time.Second).Unix() }},
+		{name: "not valid yet", edit: func(c jwt.MapClaims) { c["nbf"] = now.Add(2 * time.Minute).Unix() }, wantReason: "not_yet_valid"},
+		{name: "issued in the future within clock skew", edit: func(c jwt.MapClaims) { c["iat"] = now.Add(30 * time.Second).Unix() }},
+		{name: "issued in the future", edit: func(c jwt.MapClaims) { c["iat"] = now.Add(2 * time.Minute).Unix() }, wantReason: "used_before_issued"},
+		{name: "audience in a list", edit: func(c jwt.MapClaims) { c["aud"] = []string{"other", "audience"} }},
+		{name: "other audience", edit: func(c jwt.MapClaims) { c["aud"] = "other" }, wantReason: "invalid_audience"},
+		{name: "without audience", edit: func(c jwt.MapClaims) { delete(c, "aud") }, wantReason: "invalid_audience"},
+		{name: "audience as client_id", edit: func(c jwt.MapClaims) { delete(c, "aud"); c["client_id"] = "audience" }},
+		{name: "other client_id", edit: func(c jwt.MapClaims) { delete(c, "aud"); c["client_id"] = "other" }, wantReason: "invalid_audience"},
+		{name: "other token use", edit: func(c jwt.MapClaims) { c["token_use"] = "access" }, wantReason: "invalid_token_use"},
+		{name: "unknown issuer", edit: func(c jwt.MapClaims) { c["iss"] = "https://other.example.com" }, wantReason: "unknown_issuer"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := testClaims()
+			tc.edit(claims)
+			tokenString := signTestToken(t, jwt.SigningMethodRS256, keys[0].kid, keys[0].key, claims)
+
+			_, err := auth.ParseJWT(tokenString)
+			if tc.wantReason == "" {
+				if err != nil {
+					t.Errorf("ParseJWT() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ParseJWT() accepted the token, want %s", tc.wantReason)
+			}
+			if reason := invalidTokenReason(err); reason != tc.wantReason {
+				t.Errorf("ParseJWT() error = %v with reason %s, want %s", err, reason, tc.wantReason)
+			}
+		})
+	}
+}
+
+func TestParseJWTAlgorithms(t *testing.T) {
+	rsaKeys := newTestKeys(t, 2)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaJWK := func(kid, alg string, key *rsa.PrivateKey) JSONWebKey {
+		return JSONWebKey{
+			Alg: alg,
+			Kid: kid,
+			Kty: "RSA",
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		}
+	}
+	jwks, err := json.Marshal(JWK{Keys: []JSONWebKey{
+		rsaJWK("rsa", "", rsaKeys[0].key),
+		rsaJWK("rsa-rs256", "RS256", rsaKeys[0].key),
+		{
+			Kid: "ec",
+			Kty: "EC",
+			Crv: "P-256",
+			X:   base64.RawURLEncoding.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+			Y:   base64.RawURLEncoding.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32))),
+		},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := newTestAuth(t, IssuerConfig{JWKS: jwks, Algorithms: []string{"RS256", "PS256", "ES256"}}, 0)
+
+	rsaPublicKey, err := x509.MarshalPKIXPublicKey(&rsaKeys[0].key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		method     jwt.SigningMethod
+		kid        string
+		key        interface{}
+		wantReason string
+	}{
+		{name: "RS256 with an RSA key", method: jwt.SigningMethodRS256, kid: "rsa", key: rsaKeys[0].key},
+		{name: "PS256 with an RSA key", method: jwt.SigningMethodPS256, kid: "rsa", key: rsaKeys[0].key},
+		{name: "RS256 with an RS256 key", method: jwt.SigningMethodRS256, kid: "rsa-rs256", key: rsaKeys[0].key},
+		{name: "ES256 with an EC key", method: jwt.SigningMethodES256, kid: "ec", key: ecKey},
+		{name: "PS256 with an RS256 key", method: jwt.SigningMethodPS256, kid: "rsa-rs256", key: rsaKeys[0].key, wantReason: "unsupported_algorithm"},
+		{name: "ES256 naming an RSA key", method: jwt.SigningMethodES256, kid: "rsa", key: ecKey, wantReason: "unsupported_algorithm"},
+		{name: "RS256 naming an EC key", method: jwt.SigningMethodRS256, kid: "ec", key: rsaKeys[0].key, wantReason: "unsupported_algorithm"},
+		{name: "algorithm the issuer doesn't accept", method: jwt.SigningMethodRS384, kid: "rsa", key: rsaKeys[0].key, wantReason: "unsupported_algorithm"},
+		{name: "HS256 with the public key as secret", method: jwt.SigningMethodHS256, kid: "rsa", key: rsaPublicKey, wantReason: "unsupported_algorithm"},
+		{name: "unsigned", method: jwt.SigningMethodNone, kid: "rsa", key: jwt.UnsafeAllowNoneSignatureType, wantReason: "unsupported_algorithm"},
+		{name: "unknown key", method: jwt.SigningMethodRS256, kid: "other", key: rsaKeys[0].key, wantReason: "unknown_key"},
+		{name: "signed by another key", method: jwt.SigningMethodRS256, kid: "rsa", key: rsaKeys[1].key, wantReason: "bad_signature"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tokenString := signTestToken(t, tc.method, tc.kid, tc.key, testClaims())
+
+			_, err := auth.ParseJWT(tokenString)
+			if tc.wantReason == "" {
+				if err != nil {
+					t.Errorf("ParseJWT() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("ParseJWT() accepted the token, want %s", tc.wantReason)
+			}
+			if reason := invalidTokenReason(err); reason != tc.wantReason {
+				t.Errorf("ParseJWT() error = %v with reason %s, want %s", err, reason, tc.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package authentication
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// DefaultAlgorithms are the signing algorithms accepted from an issuer that does not list its own.
+var DefaultAlgorithms = []string{"RS256"}
+
+// SupportedAlgorithms are the asymmetric signing algorithms an issuer may be configured with.
+var SupportedAlgorithms = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+var curves = map[string]elliptic.Curve{
+	"P-256": elliptic.P256(),
+	"P-384": elliptic.P384(),
+	"P-521": elliptic.P521(),
+}
+
+// curveAlgorithms are the ECDSA algorithms that use each curve.
+var curveAlgorithms = map[string]string{
+	"P-256": "ES256",
+	"P-384": "ES384",
+	"P-521": "ES512",
+}
+
+// parseKey converts a JSON Web Key to the public key type the jwt library verifies with.
+func parseKey(jwk JSONWebKey) (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		return convertKey(jwk.E, jwk.N)
+	case "EC":
+		return convertECKey(jwk.Crv, jwk.X, jwk.Y)
+	case "OKP":
+		return convertOKPKey(jwk.Crv, jwk.X)
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", jwk.Kty)
+	}
+}
+
+func convertECKey(crv, rawX, rawY string) (*ecdsa.PublicKey, error) {
+	curve, found := curves[crv]
+	if !found {
+		return nil, fmt.Errorf("unsupported curve '%s'", crv)
+	}
+	decodedX, err := base64.RawURLEncoding.DecodeString(rawX)
+	if err != nil {
+		return nil, err
+	}
+	decodedY, err := base64.RawURLEncoding.DecodeString(rawY)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(decodedX),
+		Y:     new(big.Int).SetBytes(decodedY),
+	}
+	if !curve.IsOnCurve(pubKey.X, pubKey.Y) {
+		return nil, fmt.Errorf("point is not on curve '%s'", crv)
+	}
+	return pubKey, nil
+}
+
+func convertOKPKey(crv, rawX string) (ed25519.PublicKey, error) {
+	if crv != "Ed25519" {
+		return nil, fmt.Errorf("unsupported curve '%s'", crv)
+	}
+	decodedX, err := base64.RawURLEncoding.DecodeString(rawX)
+	if err != nil {
+		return nil, err
+	}
+	if len(decodedX) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("Ed25519 key has %d bytes instead of %d", len(decodedX), ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(decodedX), nil
+}
+
+// keyAllowsAlgorithm reports whether a token signed with alg may be verified with the key.
+// Keys that name an algorithm may only be used with it.
+func keyAllowsAlgorithm(key *PublicKey, alg string) bool {
+	if key.Alg != "" && key.Alg != alg {
+		return false
+	}
+
+	switch k := key.Key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") || strings.HasPrefix(alg, "PS")
+	case *ecdsa.PublicKey:
+		return curveAlgorithms[k.Curve.Params().Name] == alg
+	case ed25519.PublicKey:
+		return alg == "EdDSA"
+	default:
+		return false
+	}
+}
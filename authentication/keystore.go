@@ -0,0 +1,254 @@
+package authentication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultKeyTTL is how long a key set is used when the response has no cache headers.
+	defaultKeyTTL = 30 * time.Minute
+	// maxKeyTTL bounds long cache lifetimes so that revoked keys do not linger.
+	maxKeyTTL = 24 * time.Hour
+	// minRefreshInterval rate-limits refreshes, including those caused by tokens with unknown key IDs.
+	minRefreshInterval = time.Minute
+)
+
+// keyStore caches an issuer's key set. It is refreshed when the cache headers of the last
+// response say it has expired, and straight away when a token names a key it does not have.
+// A failed refresh keeps the last good key set. The key set comes from a URL, or for offline
+// use from a local file or inline JSON.
+type keyStore struct {
+	file   string
+	inline []byte
+
+	mu        sync.RWMutex
+	url       string
+	jwk       *JWK
+	keys      map[string]*PublicKey
+	expiresAt time.Time
+
+	refreshMu   sync.Mutex
+	lastAttempt time.Time
+}
+
+func newKeyStore(url string, file string, inline []byte) *keyStore {
+	return &keyStore{url: url, file: file, inline: inline}
+}
+
+// PublicKey is a key from the key set with the public key the jwt library verifies with.
+type PublicKey struct {
+	JSONWebKey
+	Key interface{}
+}
+
+// HasSource reports whether the store knows where to load its key set from.
+func (k *keyStore) HasSource() bool {
+	return k.file != "" || len(k.inline) > 0 || k.URL() != ""
+}
+
+// Source describes where the key set is loaded from, for logging.
+func (k *keyStore) Source() string {
+	switch {
+	case len(k.inline) > 0:
+		return "inline JWKS"
+	case k.file != "":
+		return k.file
+	default:
+		return k.URL()
+	}
+}
+
+// URL returns where the key set is fetched from. It is empty until the issuer is discovered.
+func (k *keyStore) URL() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.url
+}
+
+func (k *keyStore) SetURL(url string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.url = url
+}
+
+// Key returns the key with the given ID, refreshing the key set if it is not known.
+func (k *keyStore) Key(kid string) (*PublicKey, bool) {
+	if key, found := k.find(kid); found {
+		return key, true
+	}
+
+	if err := k.refreshIfAllowed(); err != nil {
+		logrus.Errorf("Error refreshing JWKS from '%s' for unknown key '%s': %s", k.Source(), kid, err.Error())
+	}
+	return k.find(kid)
+}
+
+func (k *keyStore) find(kid string) (*PublicKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, found := k.keys[kid]
+	return key, found
+}
+
+// JWK returns the current key set.
+func (k *keyStore) JWK() *JWK {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.jwk
+}
+
+// nextRefresh returns how long until the key set should be refreshed.
+func (k *keyStore) nextRefresh() time.Duration {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	wait := time.Until(k.expiresAt)
+	if wait < minRefreshInterval {
+		return minRefreshInterval
+	}
+	return wait
+}
+
+// refreshIfAllowed refreshes the key set unless another refresh was attempted within
+// minRefreshInterval. Concurrent callers wait for a single refresh.
+func (k *keyStore) refreshIfAllowed() error {
+	k.refreshMu.Lock()
+	defer k.refreshMu.Unlock()
+	if time.Since(k.lastAttempt) < minRefreshInterval {
+		return nil
+	}
+	return k.refreshLocked()
+}
+
+// Refresh fetches the key set regardless of the rate limit.
+func (k *keyStore) Refresh() error {
+	k.refreshMu.Lock()
+	defer k.refreshMu.Unlock()
+	return k.refreshLocked()
+}
+
+func (k *keyStore) refreshLocked() error {
+	k.lastAttempt = time.Now()
+	source := k.Source()
+
+	body, ttl, err := k.fetch()
+	if err != nil {
+		return err
+	}
+
+	jwk := new(JWK)
+	if err = json.Unmarshal(body, jwk); err != nil {
+		return err
+	}
+
+	keys := make(map[string]*PublicKey, len(jwk.Keys))
+	for _, v := range jwk.Keys {
+		key, err := parseKey(v)
+		if err != nil {
+			logrus.Errorf("Skipping key '%s' from '%s': %s", v.Kid, source, err.Error())
+			continue
+		}
+		keys[v.Kid] = &PublicKey{JSONWebKey: v, Key: key}
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("JWKS from '%s' has no usable keys", source)
+	}
+
+	k.mu.Lock()
+	k.jwk = jwk
+	k.keys = keys
+	k.expiresAt = time.Now().Add(ttl)
+	k.mu.Unlock()
+
+	return nil
+}
+
+// fetch returns the raw key set and how long it may be used for.
+func (k *keyStore) fetch() ([]byte, time.Duration, error) {
+	if len(k.inline) > 0 {
+		return k.inline, maxKeyTTL, nil
+	}
+	if k.file != "" {
+		body, err := os.ReadFile(k.file)
+		return body, defaultKeyTTL, err
+	}
+
+	req, err := http.NewRequest("GET", k.URL(), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.Header.Add("Accept", "application/json")
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("unexpected status %d fetching JWKS", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return body, cacheTTL(resp.Header), nil
+}
+
+// cacheTTL reads how long a response may be cached from its Cache-Control or Expires header.
+func cacheTTL(header http.Header) time.Duration {
+	ttl := defaultKeyTTL
+
+	if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+		ttl = time.Until(expires)
+	}
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.TrimSpace(strings.ToLower(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			ttl = 0
+		case strings.HasPrefix(directive, "max-age="):
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+				ttl = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+
+	if ttl < minRefreshInterval {
+		return minRefreshInterval
+	}
+	if ttl > maxKeyTTL {
+		return maxKeyTTL
+	}
+	return ttl
+}
+
+// run refreshes the key set whenever it expires, until the context is done.
+func (k *keyStore) run(ctx context.Context) {
+	for {
+		timer := time.NewTimer(k.nextRefresh())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		logrus.Infof("Refreshing JWKS from '%s'", k.Source())
+		if err := k.Refresh(); err != nil {
+			logrus.Errorf("Error refreshing JWKS from '%s', keeping the last good key set: %s", k.Source(), err.Error())
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package authentication
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/pkg/errors"
+)
+
+// SessionCookieName is the cookie that carries a browser's session.
+const SessionCookieName = "maroon_session"
+
+// SessionKeySize is the size of the AES-256 key that encrypts session cookies.
+const SessionKeySize = 32
+
+// sessionCipher encrypts and authenticates session cookies with AES-GCM, so that browsers
+// can neither read nor forge the token inside.
+type sessionCipher struct {
+	aead cipher.AEAD
+}
+
+func newSessionCipher(key []byte) (*sessionCipher, error) {
+	if len(key) != SessionKeySize {
+		return nil, fmt.Errorf("session key must be %d bytes, not %d", SessionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sessionCipher{aead: aead}, nil
+}
+
+func (c *sessionCipher) seal(token string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(token), []byte(SessionCookieName))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (c *sessionCipher) open(value string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return "", ErrInvalidSession
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	token, err := c.aead.Open(nil, nonce, ciphertext, []byte(SessionCookieName))
+	if err != nil {
+		return "", ErrInvalidSession
+	}
+	return string(token), nil
+}
+
+// SealSession encrypts a token into a session cookie value that JWTMiddleware accepts.
+func (a *Auth) SealSession(token string) (string, error) {
+	if a.sessions == nil {
+		return "", ErrSessionsDisabled
+	}
+	return a.sessions.seal(token)
+}
+
+// SessionsEnabled reports whether session cookies can be issued and accepted.
+func (a *Auth) SessionsEnabled() bool {
+	return a.sessions != nil
+}
+
+// LoadSessionKey reads a base64-encoded session key from a Secrets Manager secret.
+func LoadSessionKey(secretId string) ([]byte, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating config")
+	}
+	client := secretsmanager.NewFromConfig(cfg)
+
+	output, err := client.GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretId),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting session key")
+	}
+
+	secret := aws.ToString(output.SecretString)
+	if secret == "" {
+		return nil, errors.Errorf("Session key secret '%s' has no secret string", secretId)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing session key")
+	}
+
+	return key, nil
+}
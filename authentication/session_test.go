@@ -0,0 +1,138 @@
+package authentication
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+)
+
+func newTestSessionCipher(t *testing.T) *sessionCipher {
+	t.Helper()
+	c, err := newSessionCipher(bytes.Repeat([]byte{1}, SessionKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestNewSessionCipherKeySize(t *testing.T) {
+	for _, size := range []int{0, 16, 31, 33} {
+		if _, err := newSessionCipher(make([]byte, size)); err == nil {
+			t.Errorf("newSessionCipher accepted a %d-byte key", size)
+		}
+	}
+}
+
+func TestSessionCipherRoundTrip(t *testing.T) {
+	c := newTestSessionCipher(t)
+
+	sealed, err := c.seal("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed, "token") {
+		t.Errorf("sealed session %q contains the token", sealed)
+	}
+	opened, err := c.open(sealed)
+	if err != nil || opened != "token" {
+		t.Errorf("open() = %q, %v, want the token", opened, err)
+	}
+
+	again, err := c.seal("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == sealed {
+		t.Error("sealing the same token twice gave the same value")
+	}
+}
+
+func TestSessionCipherRejectsTampering(t *testing.T) {
+	c := newTestSessionCipher(t)
+	sealed, err := c.seal("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(sealed)
+	flipped := append([]byte{}, raw...)
+	flipped[len(flipped)-1] ^= 1
+
+	other, err := newSessionCipher(bytes.Repeat([]byte{2}, SessionKeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSealed, err := other.seal("token")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, value := range map[string]string{
+		"flipped bit":   base64.RawURLEncoding.EncodeToString(flipped),
+		"truncated":     base64.RawURLEncoding.EncodeToString(raw[:4]),
+		"not base64":    "!!!",
+		"empty":         "",
+		"other key":     otherSealed,
+		"plain token":   "token",
+		"sealed prefix": sealed[:len(sealed)-2],
+	} {
+		if _, err := c.open(value); !errors.Is(err, ErrInvalidSession) {
+			t.Errorf("%s: open() error = %v, want ErrInvalidSession", name, err)
+		}
+	}
+}
+
+func TestSessionCookieOnlyOnSafeMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keys := newTestKeys(t, 1)
+	auth, err := NewAuth(context.Background(), &Config{
+		Issuers:    []IssuerConfig{{Issuer: testIssuer, JWKS: testJWKS(t, keys)}},
+		SessionKey: bytes.Repeat([]byte{1}, SessionKeySize),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(auth.Close)
+
+	token := signTestToken(t, jwt.SigningMethodRS256, keys[0].kid, keys[0].key, testClaims())
+	session, err := auth.SealSession(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(JWTMiddleware(*auth))
+	router.Any("/", func(ctx *gin.Context) { ctx.Status(http.StatusNoContent) })
+
+	for _, tc := range []struct {
+		method string
+		cookie string
+		header string
+		want   int
+	}{
+		{method: http.MethodGet, cookie: session, want: http.StatusNoContent},
+		{method: http.MethodHead, cookie: session, want: http.StatusNoContent},
+		{method: http.MethodPost, cookie: session, want: http.StatusUnauthorized},
+		{method: http.MethodDelete, cookie: session, want: http.StatusUnauthorized},
+		{method: http.MethodPost, cookie: session, header: "Bearer " + token, want: http.StatusNoContent},
+		{method: http.MethodGet, cookie: "tampered", want: http.StatusUnauthorized},
+	} {
+		req := httptest.NewRequest(tc.method, "/", nil)
+		req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: tc.cookie})
+		if tc.header != "" {
+			req.Header.Set("Authorization", tc.header)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Errorf("%s with cookie %.10q and header %.10q: status %d, want %d", tc.method, tc.cookie, tc.header, w.Code, tc.want)
+		}
+	}
+}
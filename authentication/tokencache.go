@@ -0,0 +1,78 @@
+package authentication
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// tokenCache is a bounded LRU of tokens that have already been validated, so that repeat
+// requests with the same token skip signature and claim checks until the token expires.
+// Tokens are keyed by their SHA-256 hash rather than kept in memory.
+type tokenCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[[sha256.Size]byte]*list.Element
+	order    *list.List
+}
+
+type tokenCacheEntry struct {
+	hash      [sha256.Size]byte
+	claims    jwt.MapClaims
+	expiresAt time.Time
+}
+
+func newTokenCache(capacity int) *tokenCache {
+	return &tokenCache{
+		capacity: capacity,
+		entries:  make(map[[sha256.Size]byte]*list.Element, capacity),
+		order:    list.New(),
+	}
+}
+
+// Get returns the claims of a validated token that has not expired yet.
+func (c *tokenCache) Get(tokenString string) (jwt.MapClaims, bool) {
+	hash := sha256.Sum256([]byte(tokenString))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, found := c.entries[hash]
+	if !found {
+		return nil, false
+	}
+	entry := element.Value.(*tokenCacheEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, hash)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.claims, true
+}
+
+// Add caches the claims of a validated token until its exp claim.
+func (c *tokenCache) Add(tokenString string, claims jwt.MapClaims) {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return
+	}
+	hash := sha256.Sum256([]byte(tokenString))
+	entry := &tokenCacheEntry{hash: hash, claims: claims, expiresAt: time.Unix(int64(exp), 0)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, found := c.entries[hash]; found {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[hash] = c.order.PushFront(entry)
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*tokenCacheEntry).hash)
+	}
+}
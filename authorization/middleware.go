@@ -0,0 +1,50 @@
+package authorization
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/authentication"
+	"github.com/hunoz/maroon-api/ginctx"
+)
+
+var contextKey = ginctx.Key[Authorizer]("authorizer")
+
+// Authorizer decides whether a caller may use an account, role or access type.
+type Authorizer interface {
+	Authorize(req Request) Decision
+	IsAdmin(req Request) bool
+}
+
+// Middleware makes the authorizer available to the handlers for the request.
+func Middleware(authorizer Authorizer) gin.HandlerFunc {
+	return contextKey.Middleware(authorizer)
+}
+
+// FromContext returns the request's authorizer. Without one every request is denied.
+func FromContext(ctx *gin.Context) Authorizer {
+	if authorizer, exists := contextKey.Get(ctx); exists {
+		return authorizer
+	}
+	return NewPolicy(nil)
+}
+
+// AuthenticationFromContext returns how the caller authenticated, from the amr, auth_time
+// and token_use claims of their token.
+func AuthenticationFromContext(ctx *gin.Context) Authentication {
+	claims := authentication.GetClaims(ctx)
+	authn := Authentication{}
+
+	authn.TokenUse, _ = claims["token_use"].(string)
+	if methods, ok := claims["amr"].([]interface{}); ok {
+		for _, v := range methods {
+			authn.Methods = append(authn.Methods, fmt.Sprint(v))
+		}
+	}
+	if authTime, ok := claims["auth_time"].(float64); ok {
+		authn.AuthTime = time.Unix(int64(authTime), 0)
+	}
+
+	return authn
+}
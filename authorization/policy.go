@@ -0,0 +1,425 @@
+package authorization
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Wildcard matches any user, group, account, role or access type in a Rule.
+const Wildcard = "*"
+
+// PolicyVersion is the only policy document version this API understands.
+const PolicyVersion = "1"
+
+// ElevatedAccessType is never standing access. Rules cannot grant it, so it is only allowed
+// through an approved access request.
+const ElevatedAccessType = "Administrator"
+
+// Principal types, matching those of the caller's identity.
+const (
+	PrincipalTypeUser    = "user"
+	PrincipalTypeMachine = "machine"
+)
+
+// MinSessionDuration and MaxSessionDuration are the STS limits on a session, in seconds.
+// DefaultSessionDuration is what STS issues when no duration is requested.
+const (
+	MinSessionDuration     int32 = 900
+	MaxSessionDuration     int32 = 43200
+	DefaultSessionDuration int32 = 3600
+)
+
+var accountIdRegex = regexp.MustCompile(`^\d{12}$`)
+
+// Principals are who a Rule applies to: the listed users and members of the listed groups,
+// or, if neither is listed, anyone whose token matches Claims. Claims and Types further
+// restrict the principals when they are set. Users and groups are namespaced by issuer, so
+// principals only come from the listed Issuers, or from the policy's default issuer if none
+// are listed. Issuers ["*"] opts out and matches principals from every issuer.
+type Principals struct {
+	Users   []string `json:"users" yaml:"users"`
+	Groups  []string `json:"groups" yaml:"groups"`
+	Issuers []string `json:"issuers" yaml:"issuers"`
+	// Types are the principal types, "user" or "machine", that may match. Only users match if empty.
+	Types []string `json:"types" yaml:"types"`
+	// Claims maps a claim to the patterns its value must match, such as repository or ref for
+	// GitHub Actions. Nested claims are addressed with '/', as in kubernetes.io/serviceaccount/name.
+	// Any repository can get a GitHub Actions token, so principals that let machines match must
+	// pin a claim, such as repository, or users to patterns other than "*".
+	Claims map[string][]string `json:"claims" yaml:"claims"`
+}
+
+// Rule grants its principals access to the listed accounts, roles and access types.
+type Rule struct {
+	Principals         Principals `json:"principals" yaml:"principals"`
+	AccountIds         []string   `json:"accountIds" yaml:"accountIds"`
+	RoleArnPatterns    []string   `json:"roleArnPatterns" yaml:"roleArnPatterns"`
+	AccessTypes        []string   `json:"accessTypes" yaml:"accessTypes"`
+	MaxSessionDuration int32      `json:"maxSessionDuration" yaml:"maxSessionDuration"`
+}
+
+// AuthenticationRequirement is how a caller must have authenticated to use an access type.
+type AuthenticationRequirement struct {
+	// Amr lists authentication methods that must all appear in the token's amr claim.
+	Amr []string `json:"amr" yaml:"amr"`
+	// MaxAuthAge is the longest time, in seconds, since the token's auth_time.
+	MaxAuthAge int64 `json:"maxAuthAge" yaml:"maxAuthAge"`
+	// TokenUse is the required value of the token_use claim, such as "id" or "access".
+	TokenUse string `json:"tokenUse" yaml:"tokenUse"`
+}
+
+// RoleAuthenticationRequirement applies an AuthenticationRequirement to the roles matching
+// RoleArnPatterns, whatever access type they are requested as.
+type RoleAuthenticationRequirement struct {
+	RoleArnPatterns           []string `json:"roleArnPatterns" yaml:"roleArnPatterns"`
+	AuthenticationRequirement `yaml:",inline"`
+}
+
+// Authentication describes how the caller authenticated.
+type Authentication struct {
+	Methods  []string
+	AuthTime time.Time
+	TokenUse string
+}
+
+// Request describes what a caller is asking for. Empty fields are not checked, except for
+// Duration, which is checked as DefaultSessionDuration since that is what STS would issue.
+type Request struct {
+	PrincipalType  string
+	Username       string
+	Groups         []string
+	Issuer         string
+	Claims         map[string]interface{}
+	Authentication Authentication
+	AccountId      string
+	RoleArn        string
+	AccessType     string
+	Duration       int32
+}
+
+// Decision is the outcome of evaluating a Request. Allowed decisions carry the rule or
+// access grant that allowed them and the longest session, in seconds, they permit.
+// Decisions denied because the caller must authenticate again carry the unmet requirement.
+type Decision struct {
+	Allowed     bool
+	Rule        *Rule
+	GrantId     string
+	Reason      string
+	MaxDuration int32
+	StepUp      *AuthenticationRequirement
+}
+
+// Policy is a versioned, ordered list of rules. Anything that no rule allows is denied.
+// Admins may inspect decisions made for other principals. Access types listed in
+// AuthenticationRequirements, and roles matching RoleAuthenticationRequirements, are denied
+// to callers who do not meet every requirement that applies. Principals that don't list their
+// issuers come from DefaultIssuer.
+type Policy struct {
+	Version                        string                               `json:"version" yaml:"version"`
+	DefaultIssuer                  string                               `json:"defaultIssuer" yaml:"defaultIssuer"`
+	Admins                         Principals                           `json:"admins" yaml:"admins"`
+	AuthenticationRequirements     map[string]AuthenticationRequirement `json:"authenticationRequirements" yaml:"authenticationRequirements"`
+	RoleAuthenticationRequirements []RoleAuthenticationRequirement      `json:"roleAuthenticationRequirements" yaml:"roleAuthenticationRequirements"`
+	Rules                          []Rule                               `json:"rules" yaml:"rules"`
+}
+
+func NewPolicy(rules []Rule) *Policy {
+	return &Policy{Version: PolicyVersion, Rules: rules}
+}
+
+// ParsePolicy reads a JSON or YAML policy document and validates it.
+func ParsePolicy(data []byte) (*Policy, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	policy := new(Policy)
+	if err := decoder.Decode(policy); err != nil {
+		return nil, fmt.Errorf("error parsing policy document: %w", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+// Validate checks that the policy can be evaluated as its author intended.
+func (p *Policy) Validate() error {
+	if p.Version != PolicyVersion {
+		return fmt.Errorf("unsupported policy version '%s'", p.Version)
+	}
+
+	for accessType, requirement := range p.AuthenticationRequirements {
+		if requirement.MaxAuthAge < 0 {
+			return fmt.Errorf("authentication requirement for '%s' has negative max auth age", accessType)
+		}
+	}
+	for i, requirement := range p.RoleAuthenticationRequirements {
+		if len(requirement.RoleArnPatterns) == 0 {
+			return fmt.Errorf("role authentication requirement %d has no role ARN patterns", i)
+		}
+		for _, pattern := range requirement.RoleArnPatterns {
+			if pattern != Wildcard && !strings.HasPrefix(pattern, "arn:aws:iam::") {
+				return fmt.Errorf("role authentication requirement %d has invalid role ARN pattern '%s'", i, pattern)
+			}
+		}
+		if requirement.MaxAuthAge < 0 {
+			return fmt.Errorf("role authentication requirement %d has negative max auth age", i)
+		}
+	}
+
+	if contains(p.Admins.Types, PrincipalTypeMachine) && !p.Admins.pinsMachines() {
+		return fmt.Errorf("admins let machines match without pinning a claim or user")
+	}
+	for i, rule := range p.Rules {
+		if len(rule.Principals.Users) == 0 && len(rule.Principals.Groups) == 0 && len(rule.Principals.Claims) == 0 {
+			return fmt.Errorf("rule %d has no principals", i)
+		}
+		for _, principalType := range rule.Principals.Types {
+			if principalType != PrincipalTypeUser && principalType != PrincipalTypeMachine {
+				return fmt.Errorf("rule %d has invalid principal type '%s'", i, principalType)
+			}
+		}
+		if contains(rule.Principals.Types, PrincipalTypeMachine) && !rule.Principals.pinsMachines() {
+			return fmt.Errorf("rule %d lets machines match without pinning a claim or user", i)
+		}
+		if len(rule.AccountIds) == 0 {
+			return fmt.Errorf("rule %d has no account IDs", i)
+		}
+		for _, accountId := range rule.AccountIds {
+			if accountId != Wildcard && !accountIdRegex.MatchString(accountId) {
+				return fmt.Errorf("rule %d has invalid account ID '%s'", i, accountId)
+			}
+		}
+		if contains(rule.AccessTypes, ElevatedAccessType) {
+			return fmt.Errorf("rule %d grants '%s', which is only granted through access requests", i, ElevatedAccessType)
+		}
+		for _, pattern := range rule.RoleArnPatterns {
+			if pattern != Wildcard && !strings.HasPrefix(pattern, "arn:aws:iam::") {
+				return fmt.Errorf("rule %d has invalid role ARN pattern '%s'", i, pattern)
+			}
+		}
+		if rule.MaxSessionDuration != 0 && (rule.MaxSessionDuration < MinSessionDuration || rule.MaxSessionDuration > MaxSessionDuration) {
+			return fmt.Errorf("rule %d has max session duration %d outside of %d-%d seconds", i, rule.MaxSessionDuration, MinSessionDuration, MaxSessionDuration)
+		}
+	}
+
+	return nil
+}
+
+// IsAdmin reports whether the caller of the request is a policy admin.
+func (p *Policy) IsAdmin(req Request) bool {
+	return p.Admins.contains(req, p.DefaultIssuer)
+}
+
+// Authorize returns the first rule that allows the request, or a denial. ElevatedAccessType
+// is always denied, even by rules that match any access type.
+func (p *Policy) Authorize(req Request) Decision {
+	now := time.Now()
+	for _, requirement := range p.requirements(req) {
+		requirement := requirement
+		if reason := requirement.check(req.Authentication, now); reason != "" {
+			return Decision{Reason: reason, StepUp: &requirement}
+		}
+	}
+	if req.AccessType == ElevatedAccessType {
+		return Decision{Reason: fmt.Sprintf("'%s' access is only granted through approved access requests", ElevatedAccessType)}
+	}
+
+	reason := "no rule grants the caller access to the requested account, role or access type"
+	duration := req.EffectiveDuration()
+
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.Principals.contains(req, p.DefaultIssuer) {
+			continue
+		}
+		if req.AccountId != "" && !MatchesAny(rule.AccountIds, req.AccountId) {
+			continue
+		}
+		if req.RoleArn != "" && !MatchesAny(rule.RoleArnPatterns, req.RoleArn) {
+			continue
+		}
+		if req.AccessType != "" && !MatchesAny(rule.AccessTypes, req.AccessType) {
+			continue
+		}
+		if rule.MaxSessionDuration > 0 && duration > rule.MaxSessionDuration {
+			reason = fmt.Sprintf("requested duration %d exceeds the maximum of %d seconds", duration, rule.MaxSessionDuration)
+			continue
+		}
+		return Decision{Allowed: true, Rule: rule, MaxDuration: rule.EffectiveMaxDuration()}
+	}
+
+	return Decision{Reason: reason}
+}
+
+// requirements returns the authentication requirements for the request's access type and role.
+func (p *Policy) requirements(req Request) []AuthenticationRequirement {
+	requirements := []AuthenticationRequirement{}
+	if requirement, exists := p.AuthenticationRequirements[req.AccessType]; exists && req.AccessType != "" {
+		requirements = append(requirements, requirement)
+	}
+	if req.RoleArn != "" {
+		for _, requirement := range p.RoleAuthenticationRequirements {
+			if MatchesAny(requirement.RoleArnPatterns, req.RoleArn) {
+				requirements = append(requirements, requirement.AuthenticationRequirement)
+			}
+		}
+	}
+	return requirements
+}
+
+// EffectiveDuration is the session duration, in seconds, STS would issue for the request.
+func (r Request) EffectiveDuration() int32 {
+	if r.Duration <= 0 {
+		return DefaultSessionDuration
+	}
+	return r.Duration
+}
+
+// check returns why the authentication does not meet the requirement, or an empty string if it does.
+func (r *AuthenticationRequirement) check(authn Authentication, now time.Time) string {
+	for _, method := range r.Amr {
+		if !contains(authn.Methods, method) {
+			return fmt.Sprintf("authentication method '%s' is required", method)
+		}
+	}
+	if r.MaxAuthAge > 0 && (authn.AuthTime.IsZero() || now.Sub(authn.AuthTime) > time.Duration(r.MaxAuthAge)*time.Second) {
+		return fmt.Sprintf("authentication must be more recent than %d seconds", r.MaxAuthAge)
+	}
+	if r.TokenUse != "" && authn.TokenUse != r.TokenUse {
+		return fmt.Sprintf("an '%s' token is required", r.TokenUse)
+	}
+	return ""
+}
+
+// EffectiveMaxDuration is the longest session, in seconds, the rule allows.
+func (r *Rule) EffectiveMaxDuration() int32 {
+	if r.MaxSessionDuration == 0 {
+		return MaxSessionDuration
+	}
+	return r.MaxSessionDuration
+}
+
+func (p Principals) contains(req Request, defaultIssuer string) bool {
+	types := p.Types
+	if len(types) == 0 {
+		types = []string{PrincipalTypeUser}
+	}
+	if !contains(types, req.PrincipalType) {
+		return false
+	}
+	issuers := p.Issuers
+	if len(issuers) == 0 {
+		issuers = []string{defaultIssuer}
+	}
+	if req.Issuer == "" || !MatchesAny(issuers, req.Issuer) {
+		return false
+	}
+	for claim, patterns := range p.Claims {
+		if !claimMatches(req.Claims, claim, patterns) {
+			return false
+		}
+	}
+	if len(p.Users) == 0 && len(p.Groups) == 0 {
+		return len(p.Claims) > 0
+	}
+	if req.Username != "" && MatchesAny(p.Users, req.Username) {
+		return true
+	}
+	for _, group := range req.Groups {
+		if MatchesAny(p.Groups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// pinsMachines reports whether the principals only match particular machines: a claim or a
+// user must be pinned to a pattern other than "*", as anyone can get a token from a workload
+// issuer.
+func (p Principals) pinsMachines() bool {
+	for _, patterns := range p.Claims {
+		if len(patterns) > 0 && !contains(patterns, Wildcard) {
+			return true
+		}
+	}
+	return len(p.Users) > 0 && !contains(p.Users, Wildcard)
+}
+
+// claimMatches reports whether any value of the claim at path matches one of the patterns.
+func claimMatches(claims map[string]interface{}, path string, patterns []string) bool {
+	for _, value := range ClaimValues(claims, path) {
+		if MatchesAny(patterns, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClaimValues returns the claim's values as strings. A claim whose name contains '/' is looked
+// up as is before being treated as a path into nested claims.
+func ClaimValues(claims map[string]interface{}, path string) []string {
+	value, exists := claims[path]
+	if !exists {
+		parts := strings.SplitN(path, "/", 2)
+		nested, ok := claims[parts[0]].(map[string]interface{})
+		if len(parts) < 2 || !ok {
+			return nil
+		}
+		return ClaimValues(nested, parts[1])
+	}
+
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, item := range v {
+			values[i] = fmt.Sprint(item)
+		}
+		return values
+	case map[string]interface{}:
+		return nil
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesAny reports whether value matches any of the patterns, which may use '*' as a wildcard.
+func MatchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPattern supports '*' as a wildcard for any sequence of characters, including '/'.
+func matchPattern(pattern string, value string) bool {
+	if pattern == Wildcard {
+		return true
+	}
+	if !strings.Contains(pattern, Wildcard) {
+		return pattern == value
+	}
+	parts := strings.Split(pattern, Wildcard)
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	matches, _ := regexp.MatchString("^"+strings.Join(parts, ".*")+"$", value)
+	return matches
+}
@@ -0,0 +1,376 @@
+package authorization
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testAccountId = "123456789012"
+	testRoleArn   = "arn:aws:iam::123456789012:role/Deploy"
+)
+
+func testPolicy() *Policy {
+	policy := NewPolicy([]Rule{
+		{
+			Principals:  Principals{Groups: []string{"developers"}},
+			AccountIds:  []string{testAccountId},
+			AccessTypes: []string{"ReadOnly"},
+		},
+		{
+			Principals:         Principals{Users: []string{"alice"}},
+			AccountIds:         []string{Wildcard},
+			AccessTypes:        []string{"PowerUser"},
+			MaxSessionDuration: 7200,
+		},
+		{
+			Principals:      Principals{Users: []string{"bob"}, Issuers: []string{"contractors"}},
+			AccountIds:      []string{testAccountId},
+			RoleArnPatterns: []string{"arn:aws:iam::123456789012:role/Deploy*"},
+		},
+		{
+			Principals: Principals{
+				Types:   []string{PrincipalTypeMachine},
+				Issuers: []string{"github"},
+				Claims:  map[string][]string{"repository": {"my-org/my-repo"}},
+			},
+			AccountIds:      []string{testAccountId},
+			RoleArnPatterns: []string{testRoleArn},
+		},
+	})
+	policy.DefaultIssuer = "employees"
+	policy.AuthenticationRequirements = map[string]AuthenticationRequirement{
+		"PowerUser": {Amr: []string{"mfa"}, MaxAuthAge: 3600},
+	}
+	policy.RoleAuthenticationRequirements = []RoleAuthenticationRequirement{
+		{RoleArnPatterns: []string{"arn:aws:iam::*:role/DeployProd"}, AuthenticationRequirement: AuthenticationRequirement{TokenUse: "id"}},
+	}
+	return policy
+}
+
+func user(username string, groups ...string) Request {
+	return Request{PrincipalType: PrincipalTypeUser, Username: username, Groups: groups, Issuer: "employees"}
+}
+
+func TestPolicyAuthorize(t *testing.T) {
+	recentMfa := Authentication{Methods: []string{"pwd", "mfa"}, AuthTime: time.Now().Add(-time.Minute)}
+
+	for _, tc := range []struct {
+		name string
+		// edit changes the request from the default user's.
+		edit        func(*Request)
+		wantAllowed bool
+		wantRule    int
+		wantMax     int32
+		wantReason  string
+		wantStepUp  bool
+	}{
+		{
+			name: "group member gets access type",
+			edit: func(r *Request) {
+				*r = user("carol", "developers")
+				r.AccountId = testAccountId
+				r.AccessType = "ReadOnly"
+			},
+			wantAllowed: true, wantRule: 0, wantMax: MaxSessionDuration,
+		},
+		{
+			name: "group member in another account",
+			edit: func(r *Request) {
+				*r = user("carol", "developers")
+				r.AccountId = "210987654321"
+				r.AccessType = "ReadOnly"
+			},
+			wantReason: "no rule grants",
+		},
+		{
+			name: "group member with another access type",
+			edit: func(r *Request) {
+				*r = user("carol", "developers")
+				r.AccountId = testAccountId
+				r.AccessType = "PowerUser"
+			},
+			wantReason: "authentication method 'mfa' is required", wantStepUp: true,
+		},
+		{
+			name: "same group name from another issuer",
+			edit: func(r *Request) {
+				*r = user("carol", "developers")
+				r.Issuer = "contractors"
+				r.AccountId = testAccountId
+				r.AccessType = "ReadOnly"
+			},
+			wantReason: "no rule grants",
+		},
+		{
+			name: "request without an issuer",
+			edit: func(r *Request) {
+				*r = user("carol", "developers")
+				r.Issuer = ""
+				r.AccountId = testAccountId
+				r.AccessType = "ReadOnly"
+			},
+			wantReason: "no rule grants",
+		},
+		{
+			name:        "user with step-up met",
+			edit:        func(r *Request) { r.Authentication = recentMfa },
+			wantAllowed: true, wantRule: 1, wantMax: 7200,
+		},
+		{
+			name:       "user without mfa",
+			edit:       func(r *Request) { r.Authentication.AuthTime = time.Now() },
+			wantReason: "authentication method 'mfa' is required", wantStepUp: true,
+		},
+		{
+			name: "user with stale authentication",
+			edit: func(r *Request) {
+				r.Authentication = Authentication{Methods: []string{"mfa"}, AuthTime: time.Now().Add(-2 * time.Hour)}
+			},
+			wantReason: "authentication must be more recent than 3600 seconds", wantStepUp: true,
+		},
+		{
+			name:       "user without auth_time",
+			edit:       func(r *Request) { r.Authentication = Authentication{Methods: []string{"mfa"}} },
+			wantReason: "authentication must be more recent", wantStepUp: true,
+		},
+		{
+			name:        "duration within the rule's maximum",
+			edit:        func(r *Request) { r.Authentication = recentMfa; r.Duration = 7200 },
+			wantAllowed: true, wantRule: 1, wantMax: 7200,
+		},
+		{
+			name:       "duration over the rule's maximum",
+			edit:       func(r *Request) { r.Authentication = recentMfa; r.Duration = 7201 },
+			wantReason: "requested duration 7201 exceeds the maximum of 7200 seconds",
+		},
+		{
+			name:       "administrator access is never granted by rules",
+			edit:       func(r *Request) { *r = user("carol", "developers"); r.AccessType = ElevatedAccessType },
+			wantReason: "only granted through approved access requests",
+		},
+		{
+			name: "role from the rule's issuer",
+			edit: func(r *Request) {
+				*r = user("bob")
+				r.Issuer = "contractors"
+				r.AccountId = testAccountId
+				r.RoleArn = "arn:aws:iam::123456789012:role/DeployStaging"
+			},
+			wantAllowed: true, wantRule: 2, wantMax: MaxSessionDuration,
+		},
+		{
+			name: "role from the default issuer when the rule names another",
+			edit: func(r *Request) {
+				*r = user("bob")
+				r.AccountId = testAccountId
+				r.RoleArn = "arn:aws:iam::123456789012:role/DeployStaging"
+			},
+			wantReason: "no rule grants",
+		},
+		{
+			name: "role not matching the rule's patterns",
+			edit: func(r *Request) {
+				*r = user("bob")
+				r.Issuer = "contractors"
+				r.AccountId = testAccountId
+				r.RoleArn = "arn:aws:iam::123456789012:role/Admin"
+			},
+			wantReason: "no rule grants",
+		},
+		{
+			name: "role requirement without an access type",
+			edit: func(r *Request) {
+				*r = user("bob")
+				r.Issuer = "contractors"
+				r.AccountId = testAccountId
+				r.RoleArn = "arn:aws:iam::123456789012:role/DeployProd"
+				r.Authentication.TokenUse = "access"
+			},
+			wantReason: "an 'id' token is required", wantStepUp: true,
+		},
+		{
+			name: "role requirement met",
+			edit: func(r *Request) {
+				*r = user("bob")
+				r.Issuer = "contractors"
+				r.AccountId = testAccountId
+				r.RoleArn = "arn:aws:iam::123456789012:role/DeployProd"
+				r.Authentication.TokenUse = "id"
+			},
+			wantAllowed: true, wantRule: 2, wantMax: MaxSessionDuration,
+		},
+		{
+			name: "machine with pinned claims",
+			edit: func(r *Request) {
+				*r = Request{
+					PrincipalType: PrincipalTypeMachine,
+					Username:      "repo:my-org/my-repo:ref:refs/heads/main",
+					Issuer:        "github",
+					Claims:        map[string]interface{}{"repository": "my-org/my-repo"},
+					AccountId:     testAccountId,
+					RoleArn:       testRoleArn,
+				}
+			},
+			wantAllowed: true, wantRule: 3, wantMax: MaxSessionDuration,
+		},
+		{
+			name: "machine from another repository",
+			edit: func(r *Request) {
+				*r = Request{
+					PrincipalType: PrincipalTypeMachine,
+					Issuer:        "github",
+					Claims:        map[string]interface{}{"repository": "someone/else"},
+					AccountId:     testAccountId,
+					RoleArn:       testRoleArn,
+				}
+			},
+			wantReason: "no rule grants",
+		},
+		{
+			name: "user matching a machine rule's claims",
+			edit: func(r *Request) {
+				*r = user("carol")
+				r.Issuer = "github"
+				r.Claims = map[string]interface{}{"repository": "my-org/my-repo"}
+				r.AccountId = testAccountId
+				r.RoleArn = testRoleArn
+			},
+			wantReason: "no rule grants",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := testPolicy()
+			req := user("alice")
+			req.AccountId = testAccountId
+			req.AccessType = "PowerUser"
+			tc.edit(&req)
+
+			decision := policy.Authorize(req)
+			if decision.Allowed != tc.wantAllowed {
+				t.Fatalf("Allowed = %v, want %v (reason %q)", decision.Allowed, tc.wantAllowed, decision.Reason)
+			}
+			if tc.wantAllowed {
+				if decision.Rule != &policy.Rules[tc.wantRule] {
+					t.Errorf("Rule = %+v, want rule %d", decision.Rule, tc.wantRule)
+				}
+				if decision.MaxDuration != tc.wantMax {
+					t.Errorf("MaxDuration = %d, want %d", decision.MaxDuration, tc.wantMax)
+				}
+				return
+			}
+			if !strings.Contains(decision.Reason, tc.wantReason) {
+				t.Errorf("Reason = %q, want it to contain %q", decision.Reason, tc.wantReason)
+			}
+			if (decision.StepUp != nil) != tc.wantStepUp {
+				t.Errorf("StepUp = %+v, want step-up %v", decision.StepUp, tc.wantStepUp)
+			}
+		})
+	}
+}
+
+func TestPolicyAuthorizeDefaultDuration(t *testing.T) {
+	policy := NewPolicy([]Rule{{
+		Principals:         Principals{Users: []string{"alice"}},
+		AccountIds:         []string{testAccountId},
+		MaxSessionDuration: MinSessionDuration,
+	}})
+	policy.DefaultIssuer = "employees"
+	req := user("alice")
+	req.AccountId = testAccountId
+
+	// Without a duration STS issues DefaultSessionDuration, which is over the rule's maximum.
+	if decision := policy.Authorize(req); decision.Allowed {
+		t.Errorf("request without a duration was allowed past a %d second maximum", MinSessionDuration)
+	}
+	req.Duration = MinSessionDuration
+	if decision := policy.Authorize(req); !decision.Allowed {
+		t.Errorf("request within the maximum was denied: %s", decision.Reason)
+	}
+}
+
+func TestPolicyAuthorizeWildcardIssuer(t *testing.T) {
+	policy := NewPolicy([]Rule{{
+		Principals: Principals{Users: []string{"alice"}, Issuers: []string{Wildcard}},
+		AccountIds: []string{testAccountId},
+	}})
+	policy.DefaultIssuer = "employees"
+
+	for _, issuer := range []string{"employees", "contractors"} {
+		req := user("alice")
+		req.Issuer = issuer
+		if decision := policy.Authorize(req); !decision.Allowed {
+			t.Errorf("user from '%s' was denied: %s", issuer, decision.Reason)
+		}
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	valid := Rule{Principals: Principals{Users: []string{"alice"}}, AccountIds: []string{testAccountId}}
+
+	for _, tc := range []struct {
+		name    string
+		edit    func(*Policy)
+		wantErr string
+	}{
+		{name: "valid", edit: func(p *Policy) {}},
+		{name: "unknown version", edit: func(p *Policy) { p.Version = "2" }, wantErr: "unsupported policy version"},
+		{name: "no principals", edit: func(p *Policy) { p.Rules[0].Principals = Principals{} }, wantErr: "has no principals"},
+		{name: "invalid account ID", edit: func(p *Policy) { p.Rules[0].AccountIds = []string{"12345"} }, wantErr: "invalid account ID"},
+		{name: "administrator access", edit: func(p *Policy) { p.Rules[0].AccessTypes = []string{ElevatedAccessType} }, wantErr: "only granted through access requests"},
+		{name: "duration too short", edit: func(p *Policy) { p.Rules[0].MaxSessionDuration = 60 }, wantErr: "max session duration"},
+		{name: "duration too long", edit: func(p *Policy) { p.Rules[0].MaxSessionDuration = MaxSessionDuration + 1 }, wantErr: "max session duration"},
+		{
+			name: "machines pinned by claim",
+			edit: func(p *Policy) {
+				p.Rules[0].Principals = Principals{Types: []string{PrincipalTypeMachine}, Issuers: []string{"github"}, Claims: map[string][]string{"repository": {"my-org/my-repo"}}}
+			},
+		},
+		{
+			name: "machines pinned by user",
+			edit: func(p *Policy) {
+				p.Rules[0].Principals = Principals{Types: []string{PrincipalTypeMachine}, Issuers: []string{"maroon"}, Users: []string{"deploy-bot"}}
+			},
+		},
+		{
+			name: "any machine",
+			edit: func(p *Policy) {
+				p.Rules[0].Principals = Principals{Types: []string{PrincipalTypeMachine}, Issuers: []string{"github"}, Users: []string{"*"}}
+			},
+			wantErr: "without pinning a claim or user",
+		},
+		{
+			name: "machines with wildcard claims",
+			edit: func(p *Policy) {
+				p.Rules[0].Principals = Principals{Types: []string{PrincipalTypeMachine}, Issuers: []string{"github"}, Claims: map[string][]string{"repository": {"*"}}, Users: []string{"*"}}
+			},
+			wantErr: "without pinning a claim or user",
+		},
+		{
+			name:    "any machine as admin",
+			edit:    func(p *Policy) { p.Admins = Principals{Types: []string{PrincipalTypeMachine}, Groups: []string{"*"}} },
+			wantErr: "admins let machines match",
+		},
+		{
+			name:    "role requirement without patterns",
+			edit:    func(p *Policy) { p.RoleAuthenticationRequirements = []RoleAuthenticationRequirement{{}} },
+			wantErr: "no role ARN patterns",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			policy := NewPolicy([]Rule{valid})
+			tc.edit(policy)
+			err := policy.Validate()
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("Validate() error = %v, want it to contain %q", err, tc.wantErr)
+			}
+		})
+	}
+}
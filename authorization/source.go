@@ -0,0 +1,61 @@
+package authorization
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/pkg/errors"
+)
+
+// Source returns the raw contents of a policy document.
+type Source interface {
+	Load() ([]byte, error)
+}
+
+// FileSource reads the policy document from a local file.
+type FileSource struct {
+	Path string
+}
+
+func (f *FileSource) Load() ([]byte, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error reading policy file")
+	}
+	return data, nil
+}
+
+// SecretsManagerSource reads the policy document from a Secrets Manager secret string.
+type SecretsManagerSource struct {
+	SecretId string
+	client   *secretsmanager.Client
+}
+
+func NewSecretsManagerSource(secretId string) (*SecretsManagerSource, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating config")
+	}
+
+	return &SecretsManagerSource{
+		SecretId: secretId,
+		client:   secretsmanager.NewFromConfig(cfg),
+	}, nil
+}
+
+func (s *SecretsManagerSource) Load() ([]byte, error) {
+	output, err := s.client.GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(s.SecretId),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting policy secret")
+	}
+	policy := aws.ToString(output.SecretString)
+	if policy == "" {
+		return nil, errors.Errorf("Policy secret '%s' has no secret string", s.SecretId)
+	}
+	return []byte(policy), nil
+}
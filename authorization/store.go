@@ -0,0 +1,85 @@
+package authorization
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Store serves the last valid policy loaded from its source and reloads it periodically.
+type Store struct {
+	source        Source
+	defaultIssuer string
+	mu            sync.RWMutex
+	policy        *Policy
+	raw           []byte
+}
+
+// NewStore loads the policy from the source, failing if the first version is invalid,
+// and then checks the source for changes every refreshInterval. Policies that don't set
+// their default issuer get defaultIssuer.
+func NewStore(source Source, refreshInterval time.Duration, defaultIssuer string) (*Store, error) {
+	s := &Store{source: source, defaultIssuer: defaultIssuer}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+
+	ticker := time.NewTicker(refreshInterval)
+	go func() {
+		for range ticker.C {
+			if err := s.Reload(); err != nil {
+				logrus.Errorf("Error reloading policy, keeping the last valid version: %s", err.Error())
+			}
+		}
+	}()
+
+	return s, nil
+}
+
+// Reload fetches the policy document and replaces the current policy if it changed and is valid.
+func (s *Store) Reload() error {
+	raw, err := s.source.Load()
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	unchanged := s.policy != nil && bytes.Equal(raw, s.raw)
+	s.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	policy, err := ParsePolicy(raw)
+	if err != nil {
+		return err
+	}
+	if policy.DefaultIssuer == "" {
+		policy.DefaultIssuer = s.defaultIssuer
+	}
+
+	s.mu.Lock()
+	s.policy = policy
+	s.raw = raw
+	s.mu.Unlock()
+	logrus.Infof("Loaded policy with %d rules", len(policy.Rules))
+
+	return nil
+}
+
+// Policy returns the current policy.
+func (s *Store) Policy() *Policy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy
+}
+
+func (s *Store) Authorize(req Request) Decision {
+	return s.Policy().Authorize(req)
+}
+
+func (s *Store) IsAdmin(req Request) bool {
+	return s.Policy().IsAdmin(req)
+}
@@ -0,0 +1,45 @@
+package elevation
+
+import (
+	"fmt"
+
+	"github.com/hunoz/maroon-api/authorization"
+	"github.com/sirupsen/logrus"
+)
+
+// Authorizer allows requests the policy denies when the caller holds an active grant for them.
+// Grants do not lift authentication requirements.
+type Authorizer struct {
+	authorization.Authorizer
+	service *Service
+}
+
+func NewAuthorizer(authorizer authorization.Authorizer, service *Service) *Authorizer {
+	return &Authorizer{Authorizer: authorizer, service: service}
+}
+
+func (a *Authorizer) Authorize(req authorization.Request) authorization.Decision {
+	decision := a.Authorizer.Authorize(req)
+	if decision.Allowed || decision.StepUp != nil {
+		return decision
+	}
+
+	grant, err := a.service.ActiveGrant(req.Issuer, req.Username, req.AccountId, req.AccessType, req.RoleArn)
+	if err != nil {
+		logrus.Errorf("Error looking up access grants for user '%s', denying access: %s", req.Username, err.Error())
+		return authorization.Decision{Reason: "access grants could not be checked"}
+	}
+	if grant == nil {
+		return decision
+	}
+
+	remaining := int32(grant.ExpiresAt.Sub(a.service.now()).Seconds())
+	if duration := req.EffectiveDuration(); duration > remaining {
+		return authorization.Decision{
+			GrantId: grant.Id,
+			Reason:  fmt.Sprintf("requested duration %d outlasts access grant '%s', which expires in %d seconds", duration, grant.Id, remaining),
+		}
+	}
+
+	return authorization.Decision{Allowed: true, GrantId: grant.Id, MaxDuration: remaining}
+}
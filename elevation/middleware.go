@@ -0,0 +1,19 @@
+package elevation
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/ginctx"
+)
+
+var contextKey = ginctx.Key[*Service]("elevation")
+
+// Middleware makes the access request service available to the handlers for the request.
+func Middleware(service *Service) gin.HandlerFunc {
+	return contextKey.Middleware(service)
+}
+
+// FromContext returns the request's access request service, or nil if there is none.
+func FromContext(ctx *gin.Context) *Service {
+	service, _ := contextKey.Get(ctx)
+	return service
+}
@@ -0,0 +1,62 @@
+package elevation
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hunoz/maroon-api/store"
+)
+
+type Status string
+
+const (
+	StatusPending  Status = "Pending"
+	StatusApproved Status = "Approved"
+	StatusDenied   Status = "Denied"
+)
+
+var (
+	ErrNotFound        = store.ErrNotFound
+	ErrNotApprover     = errors.New("caller is not an approver")
+	ErrSelfApproval    = errors.New("requesters cannot decide their own access requests")
+	ErrAlreadyDecided  = errors.New("access request has already been decided")
+	ErrInvalidDuration = errors.New("requested grant duration is not allowed")
+)
+
+// Request is a just-in-time request for elevated access. Once approved it is a grant
+// that is valid until ExpiresAt. Requesters and approvers are identified by their issuer
+// and username, as the same username may belong to different people at different issuers.
+type Request struct {
+	Id              string    `json:"id"`
+	Requester       string    `json:"requester"`
+	RequesterIssuer string    `json:"requesterIssuer"`
+	AccountId       string    `json:"accountId"`
+	AccessType      string    `json:"accessType"`
+	RoleArn         string    `json:"roleArn"`
+	Justification   string    `json:"justification"`
+	Duration        int32     `json:"duration"`
+	Status          Status    `json:"status"`
+	Approver        string    `json:"approver,omitempty"`
+	ApproverIssuer  string    `json:"approverIssuer,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	DecidedAt       time.Time `json:"decidedAt"`
+	ExpiresAt       time.Time `json:"expiresAt"`
+}
+
+func (r Request) StoreKey() string {
+	return r.Id
+}
+
+func (r Request) StoreOrder() time.Time {
+	return r.CreatedAt
+}
+
+// IsRequestedBy reports whether the user with the given issuer and username filed the request.
+func (r *Request) IsRequestedBy(issuer, username string) bool {
+	return r.RequesterIssuer == issuer && r.Requester == username
+}
+
+// IsActive reports whether the request is an approved grant that has not expired yet.
+func (r *Request) IsActive(now time.Time) bool {
+	return r.Status == StatusApproved && now.Before(r.ExpiresAt)
+}
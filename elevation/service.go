@@ -0,0 +1,182 @@
+package elevation
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hunoz/maroon-api/authentication"
+	"github.com/hunoz/maroon-api/store"
+)
+
+// DefaultMaxDuration is how long a grant may last when Config.MaxDuration is not set.
+const DefaultMaxDuration = 4 * time.Hour
+
+// Config ...
+type Config struct {
+	// ApproverGroups are the groups whose members may decide access requests, which are only
+	// looked up at ApproverIssuer.
+	ApproverGroups []string
+	ApproverIssuer string
+	MaxDuration    time.Duration
+	Storage        Storage
+}
+
+// Service files, decides and looks up just-in-time access requests.
+type Service struct {
+	approverGroups []string
+	approverIssuer string
+	maxDuration    time.Duration
+	storage        Storage
+	now            func() time.Time
+}
+
+func NewService(config *Config) *Service {
+	s := &Service{
+		approverGroups: config.ApproverGroups,
+		approverIssuer: config.ApproverIssuer,
+		maxDuration:    config.MaxDuration,
+		storage:        config.Storage,
+		now:            time.Now,
+	}
+	if s.maxDuration == 0 {
+		s.maxDuration = DefaultMaxDuration
+	}
+	if s.storage == nil {
+		s.storage = store.NewMemory[Request]()
+	}
+	return s
+}
+
+// Submit files a pending request for access to the role behind accessType in the account.
+func (s *Service) Submit(requester *authentication.Identity, accountId, accessType, roleArn, justification string, duration int32) (*Request, error) {
+	if time.Duration(duration)*time.Second > s.maxDuration {
+		return nil, ErrInvalidDuration
+	}
+
+	id, err := store.NewId()
+	if err != nil {
+		return nil, err
+	}
+
+	req := &Request{
+		Id:              id,
+		Requester:       requester.Username,
+		RequesterIssuer: requester.Issuer,
+		AccountId:       accountId,
+		AccessType:      accessType,
+		RoleArn:         roleArn,
+		Justification:   justification,
+		Duration:        duration,
+		Status:          StatusPending,
+		CreatedAt:       s.now().UTC(),
+	}
+	if err = s.storage.Put(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// Decide approves or denies a pending request. Approval starts the grant's clock.
+func (s *Service) Decide(id string, approver *authentication.Identity, approve bool) (*Request, error) {
+	if !s.IsApprover(approver) {
+		return nil, ErrNotApprover
+	}
+
+	req, err := s.storage.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.IsRequestedBy(approver.Issuer, approver.Username) {
+		return nil, ErrSelfApproval
+	}
+	if req.Status != StatusPending {
+		return nil, ErrAlreadyDecided
+	}
+
+	previous := *req
+	now := s.now().UTC()
+	req.Approver = approver.Username
+	req.ApproverIssuer = approver.Issuer
+	req.DecidedAt = now
+	if approve {
+		req.Status = StatusApproved
+		req.ExpiresAt = now.Add(time.Duration(req.Duration) * time.Second)
+	} else {
+		req.Status = StatusDenied
+	}
+
+	// Another approver may have decided the request since it was read, in which case their
+	// decision stands.
+	err = s.storage.Replace(&previous, req)
+	if errors.Is(err, store.ErrConflict) {
+		return nil, ErrAlreadyDecided
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// IsApprover reports whether the user may decide access requests, as a member of one of the
+// approver groups at the approver issuer.
+func (s *Service) IsApprover(identity *authentication.Identity) bool {
+	if identity.Issuer == "" || identity.Issuer != s.approverIssuer {
+		return false
+	}
+	for _, group := range identity.Groups {
+		for _, approverGroup := range s.approverGroups {
+			if group == approverGroup {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Get returns a single request.
+func (s *Service) Get(id string) (*Request, error) {
+	return s.storage.Get(id)
+}
+
+// List returns every request when requester is nil, otherwise only that requester's requests.
+func (s *Service) List(requester *authentication.Identity) ([]*Request, error) {
+	requests, err := s.storage.List()
+	if err != nil || requester == nil {
+		return requests, err
+	}
+
+	filtered := make([]*Request, 0)
+	for _, req := range requests {
+		if req.IsRequestedBy(requester.Issuer, requester.Username) {
+			filtered = append(filtered, req)
+		}
+	}
+	return filtered, nil
+}
+
+// ActiveGrant returns an unexpired approved request by the user from the issuer that covers
+// the account and the access type or role ARN, if either is set. It returns an error if the
+// grants could not be read, which callers must treat as there being no grant.
+func (s *Service) ActiveGrant(issuer, username, accountId, accessType, roleArn string) (*Request, error) {
+	requests, err := s.storage.List()
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	for _, req := range requests {
+		if !req.IsRequestedBy(issuer, username) || req.AccountId != accountId || !req.IsActive(now) {
+			continue
+		}
+		if accessType != "" && req.AccessType != accessType {
+			continue
+		}
+		if roleArn != "" && req.RoleArn != roleArn {
+			continue
+		}
+		return req, nil
+	}
+	return nil, nil
+}
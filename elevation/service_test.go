@@ -0,0 +1,55 @@
+package elevation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hunoz/maroon-api/authentication"
+	"github.com/hunoz/maroon-api/store"
+)
+
+// racingStorage lets another approver decide a request right after it is read.
+type racingStorage struct {
+	*store.Memory[Request]
+	decide func(id string)
+}
+
+func (r *racingStorage) Get(key string) (*Request, error) {
+	req, err := r.Memory.Get(key)
+	if err == nil && r.decide != nil {
+		decide := r.decide
+		r.decide = nil
+		decide(key)
+	}
+	return req, err
+}
+
+func TestDecideRace(t *testing.T) {
+	storage := &racingStorage{Memory: store.NewMemory[Request]()}
+	service := NewService(&Config{ApproverGroups: []string{"approvers"}, ApproverIssuer: "issuer", Storage: storage})
+	requester := &authentication.Identity{Username: "requester", Issuer: "issuer"}
+	first := &authentication.Identity{Username: "first", Issuer: "issuer", Groups: []string{"approvers"}}
+	second := &authentication.Identity{Username: "second", Issuer: "issuer", Groups: []string{"approvers"}}
+
+	req, err := service.Submit(requester, "123456789012", "Administrator", "", "incident", 3600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage.decide = func(id string) {
+		if _, err := service.Decide(id, first, false); err != nil {
+			t.Errorf("first Decide() error = %v", err)
+		}
+	}
+	if _, err := service.Decide(req.Id, second, true); !errors.Is(err, ErrAlreadyDecided) {
+		t.Errorf("racing Decide() error = %v, want ErrAlreadyDecided", err)
+	}
+
+	decided, err := service.Get(req.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decided.Status != StatusDenied || decided.Approver != "first" {
+		t.Errorf("request is %s by %s, want the first decision to stand", decided.Status, decided.Approver)
+	}
+}
@@ -0,0 +1,6 @@
+package elevation
+
+import "github.com/hunoz/maroon-api/store"
+
+// Storage persists access requests.
+type Storage = store.Store[Request]
@@ -0,0 +1,221 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"text/template"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	awscredentials "github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/aws/smithy-go"
+	"github.com/hunoz/maroon-api/signing"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// credentialsErrorCodes are the STS errors that mean the source credentials are no longer valid,
+// such as after the IAM user's access keys have been rotated.
+var credentialsErrorCodes = []string{"InvalidClientTokenId", "SignatureDoesNotMatch", "ExpiredToken"}
+
+// Config ...
+type Config struct {
+	// Source is where the credentials roles are assumed with come from. It defaults to SourceIamUserSecret.
+	Source Source
+	// SecretId is the secret holding the IAM user's access keys. It defaults to DefaultSecretId.
+	SecretId string
+	// CredentialsTTL is how long the access keys are used before the secret is read again.
+	CredentialsTTL time.Duration
+	// WebIdentityRoleArn is the role assumed with a Maroon-signed token for SourceWebIdentity.
+	WebIdentityRoleArn string
+	// Signer signs the web identity tokens.
+	Signer *signing.Signer
+	// Chains are the hub roles assumed on the way to roles in accounts that don't trust the source.
+	Chains Chains
+	// SessionTags are the tags attached to assumed roles. No tags are attached if it is nil.
+	SessionTags *SessionTags
+	// RoleSessionNameTemplate is a text/template, executed with SessionNameData, that role
+	// sessions are named with. It defaults to DefaultRoleSessionNameTemplate.
+	RoleSessionNameTemplate string
+	// DisableSourceIdentity stops the caller from being set as the source identity of sessions,
+	// for target roles that don't allow sts:SetSourceIdentity.
+	DisableSourceIdentity bool
+}
+
+// Broker assumes roles on behalf of callers. The AWS configuration, the source credentials and
+// the STS client are set up once and reused, so a warm Lambda only calls STS for each request.
+type Broker struct {
+	config      aws.Config
+	source      Source
+	credentials *aws.CredentialsCache
+	sts         *sts.Client
+	chains      Chains
+	sessionTags *SessionTags
+
+	roleSessionName       *template.Template
+	disableSourceIdentity bool
+}
+
+// Session is a role session issued by the broker.
+type Session struct {
+	Credentials *types.Credentials
+	// Duration is the session duration in seconds, which is capped for chained sessions.
+	Duration int32
+	// Chain is the roles that were assumed before the target role.
+	Chain           []string
+	RoleSessionName string
+	SourceIdentity  string
+}
+
+func NewBroker(ctx context.Context, brokerConfig *Config) (*Broker, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Error creating config")
+	}
+
+	if err := brokerConfig.Chains.Validate(); err != nil {
+		return nil, err
+	}
+	if err := brokerConfig.SessionTags.Validate(); err != nil {
+		return nil, err
+	}
+	roleSessionName, err := parseRoleSessionNameTemplate(brokerConfig.RoleSessionNameTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := NewSourceCredentials(cfg, brokerConfig)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("Assuming roles with source credentials from '%s'", source.Source())
+
+	credentials := aws.NewCredentialsCache(source)
+	stsConfig := cfg.Copy()
+	stsConfig.Credentials = credentials
+
+	return &Broker{
+		config:      cfg,
+		source:      source.Source(),
+		credentials: credentials,
+		sts:         sts.NewFromConfig(stsConfig),
+		chains:      brokerConfig.Chains,
+		sessionTags: brokerConfig.SessionTags,
+
+		roleSessionName:       roleSessionName,
+		disableSourceIdentity: brokerConfig.DisableSourceIdentity,
+	}, nil
+}
+
+// SessionTags returns the configured session tags, or nil if roles are assumed without tags.
+func (b *Broker) SessionTags() *SessionTags {
+	return b.sessionTags
+}
+
+// AssumeRole assumes a role, first walking the role chain configured for its account. Chained
+// sessions are capped at MaxChainedDuration, and the returned session has the duration that
+// was actually requested from STS. The input's source identity and session tags are set on the
+// first role in the chain, after which only the tags that aren't transitive are set again.
+func (b *Broker) AssumeRole(ctx context.Context, input *sts.AssumeRoleInput) (*Session, error) {
+	chain := b.chains.For(aws.ToString(input.RoleArn))
+
+	duration := aws.ToInt32(input.DurationSeconds)
+	if duration == 0 {
+		duration = DefaultDuration
+	}
+	if (len(chain) > 0 || b.source.IsRoleSession()) && duration > MaxChainedDuration {
+		duration = MaxChainedDuration
+	}
+
+	client := b.sts
+	for i, hop := range chain {
+		hopInput := *input
+		if i > 0 {
+			hopInput = *chainedInput(hopInput)
+		}
+		hopInput.RoleArn = aws.String(hop)
+		hopInput.DurationSeconds = aws.Int32(hopDuration)
+
+		credentials, err := b.assumeRole(ctx, client, &hopInput, i == 0)
+		if err != nil {
+			return nil, pkgerrors.Wrapf(err, "Error assuming hub role '%s'", hop)
+		}
+		client = b.chainedClient(credentials)
+	}
+
+	target := *input
+	if len(chain) > 0 {
+		target = *chainedInput(target)
+	}
+	target.DurationSeconds = aws.Int32(duration)
+	credentials, err := b.assumeRole(ctx, client, &target, len(chain) == 0)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Error Assuming Role")
+	}
+
+	return &Session{
+		Credentials: credentials,
+		Duration:    duration,
+		Chain:       chain,
+
+		RoleSessionName: aws.ToString(input.RoleSessionName),
+		SourceIdentity:  aws.ToString(input.SourceIdentity),
+	}, nil
+}
+
+// chainedInput returns the input for a role assumed with a session that already carries the
+// source identity and the transitive tags, which STS doesn't allow to be set again.
+func chainedInput(input sts.AssumeRoleInput) *sts.AssumeRoleInput {
+	tags := []types.Tag{}
+	for _, tag := range input.Tags {
+		if !containsFold(input.TransitiveTagKeys, aws.ToString(tag.Key)) {
+			tags = append(tags, tag)
+		}
+	}
+	input.Tags = tags
+	input.TransitiveTagKeys = nil
+	input.SourceIdentity = nil
+	return &input
+}
+
+// assumeRole assumes a role with client. When the client uses the source credentials and STS
+// rejects them, they are fetched again and the call is retried once.
+func (b *Broker) assumeRole(ctx context.Context, client *sts.Client, input *sts.AssumeRoleInput, fromSource bool) (*types.Credentials, error) {
+	output, err := client.AssumeRole(ctx, input)
+	if fromSource && isCredentialsError(err) {
+		logrus.Warnf("Source credentials were rejected, reloading them: %s", err.Error())
+		b.credentials.Invalidate()
+		output, err = client.AssumeRole(ctx, input)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return output.Credentials, nil
+}
+
+// chainedClient returns an STS client that uses the credentials of a hub role.
+func (b *Broker) chainedClient(credentials *types.Credentials) *sts.Client {
+	stsConfig := b.config.Copy()
+	stsConfig.Credentials = aws.NewCredentialsCache(awscredentials.NewStaticCredentialsProvider(
+		aws.ToString(credentials.AccessKeyId),
+		aws.ToString(credentials.SecretAccessKey),
+		aws.ToString(credentials.SessionToken),
+	))
+	return sts.NewFromConfig(stsConfig)
+}
+
+func isCredentialsError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, code := range credentialsErrorCodes {
+		if apiErr.ErrorCode() == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,318 @@
+package federation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awscredentials "github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/hunoz/maroon-api/authentication"
+)
+
+const (
+	testHubRoleArn    = "arn:aws:iam::210987654321:role/Hub"
+	testSpokeRoleArn  = "arn:aws:iam::111111111111:role/Spoke"
+	testTargetRoleArn = "arn:aws:iam::123456789012:role/Target"
+	testDirectRoleArn = "arn:aws:iam::222222222222:role/Direct"
+)
+
+var credentialRegex = regexp.MustCompile(`Credential=([^/]+)/`)
+
+// stsCall is an AssumeRole request the fake STS received.
+type stsCall struct {
+	// AccessKeyId signed the request.
+	AccessKeyId string
+	Form        url.Values
+}
+
+// fakeSTS answers AssumeRole with credentials whose access key ID names the assumed role, so
+// that tests can tell which session signed each call.
+type fakeSTS struct {
+	mu    sync.Mutex
+	calls []stsCall
+}
+
+func (f *fakeSTS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	accessKeyId := ""
+	if match := credentialRegex.FindStringSubmatch(r.Header.Get("Authorization")); match != nil {
+		accessKeyId = match[1]
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, stsCall{AccessKeyId: accessKeyId, Form: r.PostForm})
+	f.mu.Unlock()
+
+	roleArn := r.PostForm.Get("RoleArn")
+	roleName := roleArn[strings.LastIndex(roleArn, "/")+1:]
+	w.Header().Set("Content-Type", "text/xml")
+	fmt.Fprintf(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+<AssumeRoleResult>
+<AssumedRoleUser><Arn>%s</Arn><AssumedRoleId>AROA:%s</AssumedRoleId></AssumedRoleUser>
+<Credentials>
+<AccessKeyId>AKID-%s</AccessKeyId>
+<SecretAccessKey>secret</SecretAccessKey>
+<SessionToken>token</SessionToken>
+<Expiration>2030-01-01T00:00:00Z</Expiration>
+</Credentials>
+</AssumeRoleResult>
+<ResponseMetadata><RequestId>request</RequestId></ResponseMetadata>
+</AssumeRoleResponse>`, roleArn, roleName, roleName)
+}
+
+// newTestBroker returns a broker whose source credentials have the access key ID "SOURCE" and
+// whose STS calls go to a fake.
+func newTestBroker(t *testing.T, source Source, chains Chains) (*Broker, *fakeSTS) {
+	t.Helper()
+	fake := &fakeSTS{}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	cfg := aws.Config{
+		Region: "us-east-1",
+		EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: server.URL}, nil
+		}),
+	}
+	credentials := aws.NewCredentialsCache(awscredentials.NewStaticCredentialsProvider("SOURCE", "secret", ""))
+	stsConfig := cfg.Copy()
+	stsConfig.Credentials = credentials
+
+	roleSessionName, err := parseRoleSessionNameTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Broker{
+		config:          cfg,
+		source:          source,
+		credentials:     credentials,
+		sts:             sts.NewFromConfig(stsConfig),
+		chains:          chains,
+		roleSessionName: roleSessionName,
+	}, fake
+}
+
+func testChains() Chains {
+	return Chains{"123456789012": {testHubRoleArn, testSpokeRoleArn}}
+}
+
+func TestBrokerAssumeRoleChain(t *testing.T) {
+	broker, fake := newTestBroker(t, SourceIamUserSecret, testChains())
+
+	session, err := broker.AssumeRole(context.Background(), &sts.AssumeRoleInput{
+		RoleArn:         aws.String(testTargetRoleArn),
+		RoleSessionName: aws.String("MaroonApi-alice"),
+		DurationSeconds: aws.Int32(7200),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		roleArn     string
+		accessKeyId string
+		duration    string
+	}{
+		{roleArn: testHubRoleArn, accessKeyId: "SOURCE", duration: "900"},
+		{roleArn: testSpokeRoleArn, accessKeyId: "AKID-Hub", duration: "900"},
+		{roleArn: testTargetRoleArn, accessKeyId: "AKID-Spoke", duration: "3600"},
+	}
+	if len(fake.calls) != len(want) {
+		t.Fatalf("STS was called %d times, want %d", len(fake.calls), len(want))
+	}
+	for i, w := range want {
+		call := fake.calls[i]
+		if got := call.Form.Get("RoleArn"); got != w.roleArn {
+			t.Errorf("call %d assumed %s, want %s", i, got, w.roleArn)
+		}
+		if call.AccessKeyId != w.accessKeyId {
+			t.Errorf("call %d was signed by %s, want %s", i, call.AccessKeyId, w.accessKeyId)
+		}
+		if got := call.Form.Get("DurationSeconds"); got != w.duration {
+			t.Errorf("call %d requested %s seconds, want %s", i, got, w.duration)
+		}
+		if got := call.Form.Get("RoleSessionName"); got != "MaroonApi-alice" {
+			t.Errorf("call %d has role session name %q", i, got)
+		}
+	}
+
+	if aws.ToString(session.Credentials.AccessKeyId) != "AKID-Target" {
+		t.Errorf("session has access key ID %s, want the target role's", aws.ToString(session.Credentials.AccessKeyId))
+	}
+	if session.Duration != MaxChainedDuration {
+		t.Errorf("session duration = %d, want it capped at %d", session.Duration, MaxChainedDuration)
+	}
+	if len(session.Chain) != 2 || session.Chain[0] != testHubRoleArn || session.Chain[1] != testSpokeRoleArn {
+		t.Errorf("session chain = %v", session.Chain)
+	}
+}
+
+func TestBrokerAssumeRoleDuration(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		source    Source
+		roleArn   string
+		requested int32
+		want      int32
+	}{
+		{name: "direct from IAM user", source: SourceIamUserSecret, roleArn: testDirectRoleArn, requested: 7200, want: 7200},
+		{name: "direct default", source: SourceIamUserSecret, roleArn: testDirectRoleArn, want: DefaultDuration},
+		{name: "chained within the cap", source: SourceIamUserSecret, roleArn: testTargetRoleArn, requested: 1800, want: 1800},
+		{name: "chained over the cap", source: SourceIamUserSecret, roleArn: testTargetRoleArn, requested: 43200, want: MaxChainedDuration},
+		{name: "from the Lambda role", source: SourceLambdaRole, roleArn: testDirectRoleArn, requested: 7200, want: MaxChainedDuration},
+		{name: "from a web identity role", source: SourceWebIdentity, roleArn: testDirectRoleArn, requested: 7200, want: MaxChainedDuration},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			broker, fake := newTestBroker(t, tc.source, testChains())
+			input := &sts.AssumeRoleInput{RoleArn: aws.String(tc.roleArn), RoleSessionName: aws.String("MaroonApi-alice")}
+			if tc.requested != 0 {
+				input.DurationSeconds = aws.Int32(tc.requested)
+			}
+
+			session, err := broker.AssumeRole(context.Background(), input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if session.Duration != tc.want {
+				t.Errorf("session duration = %d, want %d", session.Duration, tc.want)
+			}
+			last := fake.calls[len(fake.calls)-1]
+			if got := last.Form.Get("DurationSeconds"); got != fmt.Sprint(tc.want) {
+				t.Errorf("STS was asked for %s seconds, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+// formTags returns the session tags and transitive tag keys of an AssumeRole request.
+func formTags(form url.Values) (map[string]string, []string) {
+	tags := map[string]string{}
+	for i := 1; form.Get(fmt.Sprintf("Tags.member.%d.Key", i)) != ""; i++ {
+		tags[form.Get(fmt.Sprintf("Tags.member.%d.Key", i))] = form.Get(fmt.Sprintf("Tags.member.%d.Value", i))
+	}
+	transitive := []string{}
+	for i := 1; form.Get(fmt.Sprintf("TransitiveTagKeys.member.%d", i)) != ""; i++ {
+		transitive = append(transitive, form.Get(fmt.Sprintf("TransitiveTagKeys.member.%d", i)))
+	}
+	return tags, transitive
+}
+
+func TestBrokerAssumeRoleTransitiveTags(t *testing.T) {
+	allTags := map[string]string{"Username": "alice", "Groups": "developers", "AccessType": "ReadOnly"}
+	nonTransitiveTags := map[string]string{"Groups": "developers", "AccessType": "ReadOnly"}
+
+	for _, tc := range []struct {
+		name    string
+		roleArn string
+		// want are the tags and transitive keys of each call, in order.
+		want []map[string]string
+	}{
+		{name: "direct", roleArn: testDirectRoleArn, want: []map[string]string{allTags}},
+		{name: "chained", roleArn: testTargetRoleArn, want: []map[string]string{allTags, nonTransitiveTags, nonTransitiveTags}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			broker, fake := newTestBroker(t, SourceIamUserSecret, testChains())
+			_, err := broker.AssumeRole(context.Background(), &sts.AssumeRoleInput{
+				RoleArn:         aws.String(tc.roleArn),
+				RoleSessionName: aws.String("MaroonApi-alice"),
+				Tags: []types.Tag{
+					{Key: aws.String("Username"), Value: aws.String("alice")},
+					{Key: aws.String("Groups"), Value: aws.String("developers")},
+					{Key: aws.String("AccessType"), Value: aws.String("ReadOnly")},
+				},
+				TransitiveTagKeys: []string{"username"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(fake.calls) != len(tc.want) {
+				t.Fatalf("STS was called %d times, want %d", len(fake.calls), len(tc.want))
+			}
+			for i, wantTags := range tc.want {
+				tags, transitive := formTags(fake.calls[i].Form)
+				if fmt.Sprint(tags) != fmt.Sprint(wantTags) {
+					t.Errorf("call %d has tags %v, want %v", i, tags, wantTags)
+				}
+				wantTransitive := "[]"
+				if i == 0 {
+					wantTransitive = "[username]"
+				}
+				if fmt.Sprint(transitive) != wantTransitive {
+					t.Errorf("call %d has transitive tag keys %v, want %s", i, transitive, wantTransitive)
+				}
+			}
+		})
+	}
+}
+
+func TestBrokerAssumeRoleSourceIdentity(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		roleArn string
+		// want is the source identity of each call, in order.
+		want []string
+	}{
+		{name: "direct", roleArn: testDirectRoleArn, want: []string{"alice"}},
+		{name: "chained", roleArn: testTargetRoleArn, want: []string{"alice", "", ""}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			broker, fake := newTestBroker(t, SourceIamUserSecret, testChains())
+			session, err := broker.AssumeRole(context.Background(), &sts.AssumeRoleInput{
+				RoleArn:         aws.String(tc.roleArn),
+				RoleSessionName: aws.String("MaroonApi-alice"),
+				SourceIdentity:  aws.String("alice"),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(fake.calls) != len(tc.want) {
+				t.Fatalf("STS was called %d times, want %d", len(fake.calls), len(tc.want))
+			}
+			for i, want := range tc.want {
+				if got := fake.calls[i].Form.Get("SourceIdentity"); got != want {
+					t.Errorf("call %d has source identity %q, want %q", i, got, want)
+				}
+			}
+			if session.SourceIdentity != "alice" || session.RoleSessionName != "MaroonApi-alice" {
+				t.Errorf("session has source identity %q and role session name %q", session.SourceIdentity, session.RoleSessionName)
+			}
+		})
+	}
+}
+
+func TestBrokerSourceIdentity(t *testing.T) {
+	broker, _ := newTestBroker(t, SourceIamUserSecret, nil)
+	for _, tc := range []struct {
+		identity authentication.Identity
+		want     string
+	}{
+		{identity: authentication.Identity{Username: "alice", Subject: "subject"}, want: "alice"},
+		{identity: authentication.Identity{Username: "alice smith/admin", Subject: "subject"}, want: "alicesmithadmin"},
+		{identity: authentication.Identity{Username: "ä", Subject: "subject"}, want: "subject"},
+		{identity: authentication.Identity{Username: strings.Repeat("a", 100)}, want: strings.Repeat("a", maxSessionNameLength)},
+		{identity: authentication.Identity{Username: "ä", Subject: "ö"}, want: ""},
+	} {
+		if got := broker.SourceIdentity(&tc.identity); got != tc.want {
+			t.Errorf("SourceIdentity(%+v) = %q, want %q", tc.identity, got, tc.want)
+		}
+	}
+
+	broker.disableSourceIdentity = true
+	if got := broker.SourceIdentity(&authentication.Identity{Username: "alice"}); got != "" {
+		t.Errorf("SourceIdentity() = %q with source identities disabled", got)
+	}
+}
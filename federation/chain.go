@@ -0,0 +1,53 @@
+package federation
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+var (
+	accountIdRegex = regexp.MustCompile(`^\d{12}$`)
+	// roleArnRegex matches IAM role ARNs in any partition, with or without a path.
+	roleArnRegex = regexp.MustCompile(`^arn:aws[a-z-]*:iam::\d{12}:role/(?:[!-~]*/)?[0-9A-Za-z_+=,.@-]{1,64}$`)
+)
+
+const (
+	// MaxChainedDuration is the longest session, in seconds, STS issues for a role assumed with
+	// the credentials of another role.
+	MaxChainedDuration int32 = 3600
+	// DefaultDuration is the session duration STS uses when none is requested.
+	DefaultDuration int32 = 3600
+	// hopDuration only needs to outlast the call that assumes the next role in the chain.
+	hopDuration int32 = 900
+)
+
+// Chains maps a target account ID to the roles assumed, in order, before the target role. The
+// first role is assumed with the source credentials, and each following role with the one
+// before it.
+type Chains map[string][]string
+
+// Validate checks that the chains are keyed by account ID and only contain role ARNs.
+func (c Chains) Validate() error {
+	for accountId, hops := range c {
+		if !accountIdRegex.MatchString(accountId) {
+			return fmt.Errorf("role chain for '%s' is not keyed by an account ID", accountId)
+		}
+		for _, hop := range hops {
+			if !roleArnRegex.MatchString(hop) {
+				return fmt.Errorf("role chain for '%s' contains '%s', which is not a role ARN", accountId, hop)
+			}
+		}
+	}
+	return nil
+}
+
+// For returns the roles assumed before roleArn, or nil if it is assumed with the source credentials.
+func (c Chains) For(roleArn string) []string {
+	parsed, err := arn.Parse(roleArn)
+	if err != nil {
+		return nil
+	}
+	return c[parsed.AccountID]
+}
@@ -0,0 +1,32 @@
+package federation
+
+import "testing"
+
+func TestChainsValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		chains  Chains
+		wantErr bool
+	}{
+		{name: "empty", chains: Chains{}},
+		{name: "one hop", chains: Chains{"123456789012": {"arn:aws:iam::210987654321:role/Hub"}}},
+		{name: "role with path", chains: Chains{"123456789012": {"arn:aws:iam::210987654321:role/maroon/Hub"}}},
+		{name: "other partition", chains: Chains{"123456789012": {"arn:aws-us-gov:iam::210987654321:role/Hub"}}},
+		{name: "two hops", chains: Chains{"123456789012": {"arn:aws:iam::210987654321:role/Hub", "arn:aws:iam::111111111111:role/Spoke"}}},
+		{name: "account ID with letters", chains: Chains{"12345678901a": {"arn:aws:iam::210987654321:role/Hub"}}, wantErr: true},
+		{name: "short account ID", chains: Chains{"12345678901": {"arn:aws:iam::210987654321:role/Hub"}}, wantErr: true},
+		{name: "user ARN", chains: Chains{"123456789012": {"arn:aws:iam::210987654321:user/Hub"}}, wantErr: true},
+		{name: "other service", chains: Chains{"123456789012": {"arn:aws:s3:::bucket"}}, wantErr: true},
+		{name: "role without name", chains: Chains{"123456789012": {"arn:aws:iam::210987654321:role/"}}, wantErr: true},
+		{name: "hop account ID with letters", chains: Chains{"123456789012": {"arn:aws:iam::21098765432x:role/Hub"}}, wantErr: true},
+		{name: "not an ARN", chains: Chains{"123456789012": {"Hub"}}, wantErr: true},
+		{name: "second hop invalid", chains: Chains{"123456789012": {"arn:aws:iam::210987654321:role/Hub", "arn:aws:iam::111111111111:policy/Spoke"}}, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.chains.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Validate() error = %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package federation
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/ginctx"
+)
+
+var contextKey = ginctx.Key[*Broker]("federation")
+
+// Middleware makes the role broker available to the handlers for the request.
+func Middleware(broker *Broker) gin.HandlerFunc {
+	return contextKey.Middleware(broker)
+}
+
+// FromContext returns the request's role broker, or nil if there is none.
+func FromContext(ctx *gin.Context) *Broker {
+	broker, _ := contextKey.Get(ctx)
+	return broker
+}
@@ -0,0 +1,93 @@
+package federation
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/hunoz/maroon-api/authentication"
+	pkgerrors "github.com/pkg/errors"
+)
+
+const (
+	// DefaultRoleSessionNameTemplate names sessions after the caller, as Maroon always has.
+	DefaultRoleSessionNameTemplate = "MaroonApi-{{.Username}}"
+	// fallbackRoleSessionName is used when the template leaves too little to be a valid name.
+	fallbackRoleSessionName = "MaroonApi"
+	// minSessionNameLength and maxSessionNameLength are STS's limits for both the role session
+	// name and the source identity.
+	minSessionNameLength = 2
+	maxSessionNameLength = 64
+)
+
+// SessionNameData is what the role session name template is executed with.
+type SessionNameData struct {
+	Username   string
+	Email      string
+	Subject    string
+	Issuer     string
+	AccessType string
+	AccountId  string
+}
+
+func parseRoleSessionNameTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		text = DefaultRoleSessionNameTemplate
+	}
+	tmpl, err := template.New("roleSessionName").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, pkgerrors.Wrap(err, "Error parsing role session name template")
+	}
+	if err := tmpl.Execute(&strings.Builder{}, SessionNameData{}); err != nil {
+		return nil, pkgerrors.Wrap(err, "Error executing role session name template")
+	}
+	return tmpl, nil
+}
+
+// RoleSessionName returns the role session name for the caller from the configured template.
+func (b *Broker) RoleSessionName(identity *authentication.Identity, accessType string, accountId string) (string, error) {
+	name := &strings.Builder{}
+	if err := b.roleSessionName.Execute(name, SessionNameData{
+		Username:   identity.Username,
+		Email:      identity.Email,
+		Subject:    identity.Subject,
+		Issuer:     identity.Issuer,
+		AccessType: accessType,
+		AccountId:  accountId,
+	}); err != nil {
+		return "", pkgerrors.Wrap(err, "Error executing role session name template")
+	}
+
+	if sanitized := sanitizeSessionName(name.String()); len(sanitized) >= minSessionNameLength {
+		return sanitized, nil
+	}
+	return fallbackRoleSessionName, nil
+}
+
+// SourceIdentity returns the source identity for the caller: their username, or their subject if
+// the username can't be used. It is empty if source identities are disabled or neither can be used.
+func (b *Broker) SourceIdentity(identity *authentication.Identity) string {
+	if b.disableSourceIdentity {
+		return ""
+	}
+	for _, value := range []string{identity.Username, identity.Subject} {
+		if sanitized := sanitizeSessionName(value); len(sanitized) >= minSessionNameLength {
+			return sanitized
+		}
+	}
+	return ""
+}
+
+// sanitizeSessionName strips the characters STS doesn't allow in role session names and source
+// identities, which are anything but letters, numbers and _+=,.@-, and truncates to their limit.
+func sanitizeSessionName(value string) string {
+	sanitized := []byte{}
+	for _, r := range value {
+		if ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') || strings.ContainsRune("_+=,.@-", r) {
+			sanitized = append(sanitized, byte(r))
+		}
+	}
+	if len(sanitized) > maxSessionNameLength {
+		sanitized = sanitized[:maxSessionNameLength]
+	}
+	return string(sanitized)
+}
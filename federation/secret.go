@@ -0,0 +1,77 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultSecretId is the secret holding the IAM user's access keys.
+const DefaultSecretId = "MaroonApiIamUser"
+
+// DefaultCredentialsTTL is how long IAM user access keys are cached when Config.CredentialsTTL is not set.
+const DefaultCredentialsTTL = 15 * time.Minute
+
+type IamCredentials struct {
+	AccessKeyId     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+}
+
+// SecretCredentialsProvider reads IAM user access keys from a Secrets Manager secret. The keys
+// expire after ttl so that a rotated secret is picked up; wrap the provider in an
+// aws.CredentialsCache to only read the secret when that happens.
+type SecretCredentialsProvider struct {
+	client   *secretsmanager.Client
+	secretId string
+	ttl      time.Duration
+
+	mu        sync.Mutex
+	versionId string
+}
+
+func NewSecretCredentialsProvider(client *secretsmanager.Client, secretId string, ttl time.Duration) *SecretCredentialsProvider {
+	return &SecretCredentialsProvider{client: client, secretId: secretId, ttl: ttl}
+}
+
+func (p *SecretCredentialsProvider) Source() Source {
+	return SourceIamUserSecret
+}
+
+func (p *SecretCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	output, err := p.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(p.secretId),
+	})
+	if err != nil {
+		return aws.Credentials{}, errors.Wrap(err, "Error getting IAM user credentials")
+	}
+
+	var credentials IamCredentials
+	if err = json.Unmarshal([]byte(aws.ToString(output.SecretString)), &credentials); err != nil {
+		return aws.Credentials{}, errors.Wrap(err, "Error parsing IAM user credentials")
+	}
+	if credentials.AccessKeyId == "" || credentials.SecretAccessKey == "" {
+		return aws.Credentials{}, errors.Errorf("secret '%s' does not hold IAM user credentials", p.secretId)
+	}
+
+	versionId := aws.ToString(output.VersionId)
+	p.mu.Lock()
+	if p.versionId != "" && p.versionId != versionId {
+		logrus.Infof("IAM user credentials in secret '%s' changed to version '%s'", p.secretId, versionId)
+	}
+	p.versionId = versionId
+	p.mu.Unlock()
+
+	return aws.Credentials{
+		AccessKeyID:     credentials.AccessKeyId,
+		SecretAccessKey: credentials.SecretAccessKey,
+		Source:          "SecretCredentialsProvider",
+		CanExpire:       true,
+		Expires:         time.Now().Add(p.ttl),
+	}, nil
+}
@@ -0,0 +1,122 @@
+package federation
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/golang-jwt/jwt"
+	"github.com/hunoz/maroon-api/signing"
+)
+
+// Source selects where Maroon's own AWS credentials, which it assumes roles with, come from.
+type Source string
+
+const (
+	// SourceLambdaRole uses the credentials of the Lambda execution role, or whatever else the
+	// default credential chain finds.
+	SourceLambdaRole Source = "lambda-role"
+	// SourceWebIdentity assumes a role with AssumeRoleWithWebIdentity and a token Maroon signs
+	// itself. The role must trust Maroon's issuer as an IAM OIDC identity provider.
+	SourceWebIdentity Source = "web-identity"
+	// SourceIamUserSecret uses long-lived IAM user access keys stored in Secrets Manager.
+	SourceIamUserSecret Source = "iam-user-secret"
+)
+
+const (
+	// WebIdentityAudience is the aud claim STS expects in web identity tokens.
+	WebIdentityAudience = "sts.amazonaws.com"
+	// WebIdentitySubject is the sub claim of Maroon's web identity tokens.
+	WebIdentitySubject = "maroon-api"
+	// webIdentityTokenLifetime only needs to outlast the AssumeRoleWithWebIdentity call.
+	webIdentityTokenLifetime = 5 * time.Minute
+)
+
+// IsRoleSession reports whether the source credentials are themselves a role session, which makes
+// every role assumed with them a chained session.
+func (s Source) IsRoleSession() bool {
+	return s == SourceLambdaRole || s == SourceWebIdentity
+}
+
+// SourceCredentials are the credentials Maroon assumes roles with.
+type SourceCredentials interface {
+	aws.CredentialsProvider
+	// Source is the backend the credentials come from.
+	Source() Source
+}
+
+// NewSourceCredentials returns the configured source credentials, using cfg for any AWS calls
+// needed to get them.
+func NewSourceCredentials(cfg aws.Config, config *Config) (SourceCredentials, error) {
+	switch config.Source {
+	case SourceLambdaRole:
+		if cfg.Credentials == nil {
+			return nil, fmt.Errorf("no credentials found for source '%s'", config.Source)
+		}
+		return &lambdaRoleCredentials{CredentialsProvider: cfg.Credentials}, nil
+	case SourceWebIdentity:
+		if config.WebIdentityRoleArn == "" || config.Signer == nil {
+			return nil, fmt.Errorf("source '%s' needs a role ARN and a signing key", config.Source)
+		}
+		// STS fetches the issuer's keys from its discovery document, so it must be where Maroon
+		// is served from.
+		if issuer, err := url.Parse(config.Signer.Issuer()); err != nil || issuer.Scheme != "https" || issuer.Host == "" {
+			return nil, fmt.Errorf("source '%s' needs an https:// issuer URL, not '%s'", config.Source, config.Signer.Issuer())
+		}
+		provider := stscreds.NewWebIdentityRoleProvider(
+			sts.NewFromConfig(cfg),
+			config.WebIdentityRoleArn,
+			&webIdentityToken{signer: config.Signer},
+			func(options *stscreds.WebIdentityRoleOptions) {
+				options.RoleSessionName = WebIdentitySubject
+			},
+		)
+		return &webIdentityCredentials{CredentialsProvider: provider}, nil
+	case SourceIamUserSecret, "":
+		secretId := config.SecretId
+		if secretId == "" {
+			secretId = DefaultSecretId
+		}
+		ttl := config.CredentialsTTL
+		if ttl == 0 {
+			ttl = DefaultCredentialsTTL
+		}
+		return NewSecretCredentialsProvider(secretsmanager.NewFromConfig(cfg), secretId, ttl), nil
+	default:
+		return nil, fmt.Errorf("unknown source credentials '%s'", config.Source)
+	}
+}
+
+type lambdaRoleCredentials struct {
+	aws.CredentialsProvider
+}
+
+func (c *lambdaRoleCredentials) Source() Source {
+	return SourceLambdaRole
+}
+
+type webIdentityCredentials struct {
+	aws.CredentialsProvider
+}
+
+func (c *webIdentityCredentials) Source() Source {
+	return SourceWebIdentity
+}
+
+// webIdentityToken signs a fresh token for every AssumeRoleWithWebIdentity call.
+type webIdentityToken struct {
+	signer *signing.Signer
+}
+
+func (t *webIdentityToken) GetIdentityToken() ([]byte, error) {
+	token, err := t.signer.Sign(jwt.MapClaims{
+		"sub": WebIdentitySubject,
+		"aud": WebIdentityAudience,
+		"exp": time.Now().Add(webIdentityTokenLifetime).Unix(),
+	})
+	return []byte(token), err
+}
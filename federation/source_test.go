@@ -0,0 +1,43 @@
+package federation
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/hunoz/maroon-api/signing"
+)
+
+func TestNewSourceCredentialsWebIdentityIssuer(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for issuer, wantErr := range map[string]bool{
+		"https://maroon.example.com":      false,
+		"https://maroon.example.com/prod": false,
+		"maroon-api":                      true,
+		"http://maroon.example.com":       true,
+		"https://":                        true,
+		"/maroon":                         true,
+	} {
+		signer, err := signing.NewSigner(issuer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewSourceCredentials(aws.Config{Region: "us-east-1"}, &Config{
+			Source:             SourceWebIdentity,
+			WebIdentityRoleArn: "arn:aws:iam::123456789012:role/Maroon",
+			Signer:             signer,
+		})
+		if wantErr && (err == nil || !strings.Contains(err.Error(), "https:// issuer URL")) {
+			t.Errorf("issuer %q: error = %v, want it rejected", issuer, err)
+		}
+		if !wantErr && err != nil {
+			t.Errorf("issuer %q: error = %v", issuer, err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package federation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/hunoz/maroon-api/authentication"
+	"github.com/hunoz/maroon-api/authorization"
+)
+
+const (
+	// maxSessionTags is the most session tags STS accepts on a single call.
+	maxSessionTags = 50
+	// maxTagKeyLength and maxTagValueLength are STS's limits, in characters.
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+	// tagValueSeparator joins the values of multi-valued attributes such as groups.
+	tagValueSeparator = ":"
+)
+
+// SessionTags configures the session tags attached to assumed roles. Each identity field names
+// the tag key its attribute is put under, and is left empty to not tag it.
+type SessionTags struct {
+	Username   string `json:"username"`
+	Email      string `json:"email"`
+	Groups     string `json:"groups"`
+	AccessType string `json:"accessType"`
+	// Claims maps further tag keys to the claim, or path of nested claims, their value comes from,
+	// e.g. {"CostCenter": "custom:cost_center"}.
+	Claims map[string]string `json:"claims"`
+	// TransitiveTagKeys are the tags that stay on sessions chained from the assumed role.
+	TransitiveTagKeys []string `json:"transitiveTagKeys"`
+}
+
+// Validate checks the tag keys against STS's limits.
+func (t *SessionTags) Validate() error {
+	if t == nil {
+		return nil
+	}
+
+	keys := map[string]bool{}
+	for _, key := range t.keys() {
+		if key != sanitizeTag(key, maxTagKeyLength) {
+			return fmt.Errorf("session tag key '%s' is not a valid tag key", key)
+		}
+		// Tag keys are case-insensitive in STS.
+		if keys[strings.ToLower(key)] {
+			return fmt.Errorf("session tag key '%s' is used more than once", key)
+		}
+		keys[strings.ToLower(key)] = true
+	}
+	if len(keys) > maxSessionTags {
+		return fmt.Errorf("%d session tags are configured, but STS accepts at most %d", len(keys), maxSessionTags)
+	}
+	for _, key := range t.TransitiveTagKeys {
+		if !keys[strings.ToLower(key)] {
+			return fmt.Errorf("transitive tag key '%s' is not a configured session tag", key)
+		}
+	}
+	return nil
+}
+
+func (t *SessionTags) keys() []string {
+	keys := []string{}
+	for _, key := range []string{t.Username, t.Email, t.Groups, t.AccessType} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	claimKeys := []string{}
+	for key := range t.Claims {
+		claimKeys = append(claimKeys, key)
+	}
+	sort.Strings(claimKeys)
+	return append(keys, claimKeys...)
+}
+
+// For returns the session tags for the caller and the configured transitive keys among them.
+// Attributes the caller doesn't have are left out.
+func (t *SessionTags) For(identity *authentication.Identity, claims map[string]interface{}, accessType string) ([]types.Tag, []string) {
+	if t == nil {
+		return nil, nil
+	}
+
+	values := map[string]string{
+		t.Username:   identity.Username,
+		t.Email:      identity.Email,
+		t.Groups:     strings.Join(identity.Groups, tagValueSeparator),
+		t.AccessType: accessType,
+	}
+	for key, claim := range t.Claims {
+		values[key] = strings.Join(authorization.ClaimValues(claims, claim), tagValueSeparator)
+	}
+
+	tags := []types.Tag{}
+	for _, key := range t.keys() {
+		value := sanitizeTag(values[key], maxTagValueLength)
+		if value == "" {
+			continue
+		}
+		tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+
+	transitiveTagKeys := []string{}
+	for _, key := range t.TransitiveTagKeys {
+		if hasTag(tags, key) {
+			transitiveTagKeys = append(transitiveTagKeys, key)
+		}
+	}
+	return tags, transitiveTagKeys
+}
+
+// sanitizeTag strips the characters STS doesn't allow in tags, which are anything but letters,
+// numbers, spaces and _.:/=+-@, and truncates to length characters.
+func sanitizeTag(value string, length int) string {
+	sanitized := []rune{}
+	for _, r := range value {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Zs, r) || strings.ContainsRune("_.:/=+-@", r) {
+			sanitized = append(sanitized, r)
+		}
+	}
+	if len(sanitized) > length {
+		sanitized = sanitized[:length]
+	}
+	return strings.TrimSpace(string(sanitized))
+}
+
+func hasTag(tags []types.Tag, key string) bool {
+	for _, tag := range tags {
+		if strings.EqualFold(aws.ToString(tag.Key), key) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
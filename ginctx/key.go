@@ -0,0 +1,28 @@
+package ginctx
+
+import "github.com/gin-gonic/gin"
+
+// Key is where a value of type T is kept on the gin context.
+type Key[T any] string
+
+// Middleware sets value on the context of every request.
+func (k Key[T]) Middleware(value T) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		k.Set(ctx, value)
+		ctx.Next()
+	}
+}
+
+// Set sets value on the request's context.
+func (k Key[T]) Set(ctx *gin.Context, value T) {
+	ctx.Set(string(k), value)
+}
+
+// Get returns the request's value, and whether it was set.
+func (k Key[T]) Get(ctx *gin.Context) (T, bool) {
+	if value, exists := ctx.Get(string(k)); exists {
+		return value.(T), true
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,59 @@
+/*
+Revocations, API tokens and sign-ins in progress are shared by every Lambda instance through this table
+*/
+resource "aws_dynamodb_table" "maroon_api_storage_table" {
+  name         = "MaroonApiStorage"
+  billing_mode = "PAY_PER_REQUEST"
+  hash_key     = "kind"
+  range_key    = "key"
+
+  attribute {
+    name = "kind"
+    type = "S"
+  }
+
+  attribute {
+    name = "key"
+    type = "S"
+  }
+
+  ttl {
+    attribute_name = "expiresAt"
+    enabled        = true
+  }
+
+  point_in_time_recovery {
+    enabled = true
+  }
+
+  server_side_encryption {
+    enabled = true
+  }
+}
+
+data "aws_iam_policy_document" "maroon_api_dynamodb_policy_document" {
+  policy_id = "maroon-api-lambda-dynamodb"
+  version   = "2012-10-17"
+  statement {
+    effect = "Allow"
+    actions = [
+      "dynamodb:GetItem",
+      "dynamodb:PutItem",
+      "dynamodb:DeleteItem",
+      "dynamodb:Query"
+    ]
+
+    resources = [aws_dynamodb_table.maroon_api_storage_table.arn]
+  }
+}
+
+resource "aws_iam_policy" "maroon_api_dynamodb" {
+  name   = "maroon-api-lambda-dynamodb"
+  policy = data.aws_iam_policy_document.maroon_api_dynamodb_policy_document.json
+}
+
+resource "aws_iam_role_policy_attachment" "maroon_api_dynamodb_policy_attachment" {
+  depends_on = [aws_iam_role.maroon_api_lambda_role, aws_iam_policy.maroon_api_dynamodb]
+  role       = aws_iam_role.maroon_api_lambda_role.name
+  policy_arn = aws_iam_policy.maroon_api_dynamodb.arn
+}
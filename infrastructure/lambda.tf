@@ -11,8 +11,29 @@ data "aws_iam_policy_document" "maroon_api_lambda_assume_role_policy_document" {
   }
 }
 
+resource "aws_secretsmanager_secret" "maroon_api_audiences_secret" {
+  name = "MaroonApiAudiences"
+}
+
+resource "aws_secretsmanager_secret_version" "maroon_api_audiences_secret_version" {
+  secret_id     = aws_secretsmanager_secret.maroon_api_audiences_secret.id
+  secret_string = jsonencode(var.audiences)
+}
+
+/*
+The access policy is deny-by-default, so without this secret every credential request is denied
+*/
+resource "aws_secretsmanager_secret" "maroon_api_policy_secret" {
+  name = "MaroonApiPolicy"
+}
+
+resource "aws_secretsmanager_secret_version" "maroon_api_policy_secret_version" {
+  secret_id     = aws_secretsmanager_secret.maroon_api_policy_secret.id
+  secret_string = file(var.policy_file)
+}
+
 /*
-This will grant access to the IAM secret information from the lambda
+This will grant access to the IAM, audience and policy secret information from the lambda
 */
 data "aws_iam_policy_document" "maroon_api_secretsmanager_policy_document" {
   policy_id = "maroon-api-lambda-secretsmanager"
@@ -22,7 +43,9 @@ data "aws_iam_policy_document" "maroon_api_secretsmanager_policy_document" {
     actions = ["secretsmanager:GetSecretValue"]
 
     resources = [
-      aws_secretsmanager_secret.maroon_api_user_key_secret.arn
+      aws_secretsmanager_secret.maroon_api_user_key_secret.arn,
+      aws_secretsmanager_secret.maroon_api_audiences_secret.arn,
+      aws_secretsmanager_secret.maroon_api_policy_secret.arn
     ]
   }
 }
@@ -69,9 +92,12 @@ resource "aws_lambda_function" "maroon_api_lambda" {
 
   environment {
     variables = {
-      STAGE           = "prod",
-      COGNITO_POOL_ID = var.cognito_user_pool_id,
-      COGNITO_REGION  = var.cognito_region
+      STAGE               = "prod",
+      COGNITO_POOL_ID     = var.cognito_user_pool_id,
+      COGNITO_REGION      = var.cognito_region,
+      AUDIENCES_SECRET_ID = aws_secretsmanager_secret.maroon_api_audiences_secret.name,
+      POLICY_SECRET_ID    = aws_secretsmanager_secret.maroon_api_policy_secret.name,
+      STORAGE_TABLE       = aws_dynamodb_table.maroon_api_storage_table.name
     }
   }
 }
\ No newline at end of file
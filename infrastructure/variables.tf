@@ -16,4 +16,8 @@ variable "cognito_region" {
 
 variable "audiences" {
   type = list(any)
+}
+
+variable "policy_file" {
+  type = string
 }
\ No newline at end of file
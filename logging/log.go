@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/authentication"
 	"github.com/sirupsen/logrus"
 )
 
@@ -43,13 +44,9 @@ func getClientIP(c *gin.Context) string {
 	return requester
 }
 
-// GetUserID gets the current_user ID as a string
+// GetUserID gets the current user's username as a string
 func getUserID(c *gin.Context) string {
-	userID, exists := c.Get("userID")
-	if exists {
-		return userID.(string)
-	}
-	return ""
+	return authentication.GetIdentity(c).Username
 }
 
 func SetLogMode(stage string) {
@@ -0,0 +1,157 @@
+package login
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/hunoz/maroon-api/authentication"
+)
+
+// CallbackPath is where the OIDC provider sends the browser back to after sign-in. BaseURL
+// followed by CallbackPath must be registered as a redirect URI with the provider.
+const CallbackPath = "/auth/callback"
+
+// DefaultScopes are requested from the provider when Config.Scopes is not set.
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+// Tokens is the provider's response to a successful authorization code exchange.
+type Tokens struct {
+	AccessToken string `json:"access_token"`
+	IdToken     string `json:"id_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Client signs users in with an OIDC provider using the authorization code flow with PKCE.
+type Client struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	scopes       []string
+	httpClient   *http.Client
+
+	mu       sync.Mutex
+	document *authentication.DiscoveryDocument
+}
+
+func newClient(config *Config) *Client {
+	c := &Client{
+		issuer:       config.Issuer,
+		clientID:     config.ClientID,
+		clientSecret: config.ClientSecret,
+		redirectURL:  strings.TrimSuffix(config.BaseURL, "/") + CallbackPath,
+		scopes:       config.Scopes,
+		httpClient:   &http.Client{},
+	}
+	if len(c.scopes) == 0 {
+		c.scopes = DefaultScopes
+	}
+	return c
+}
+
+// discover fetches the provider's endpoints the first time they are needed, so that an
+// unreachable provider does not stop Maroon from starting.
+func (c *Client) discover() (*authentication.DiscoveryDocument, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.document != nil {
+		return c.document, nil
+	}
+
+	document, err := authentication.Discover(c.issuer)
+	if err != nil {
+		return nil, err
+	}
+	if document.AuthorizationEndpoint == "" || document.TokenEndpoint == "" {
+		return nil, fmt.Errorf("discovery document for '%s' has no authorization or token endpoint", c.issuer)
+	}
+	c.document = document
+	return document, nil
+}
+
+// AuthCodeURL is where to send the browser to sign in. The provider returns state to the
+// callback, and the verifier must be presented when exchanging the code.
+func (c *Client) AuthCodeURL(state, verifier, nonce string) (string, error) {
+	document, err := c.discover()
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.clientID},
+		"redirect_uri":          {c.redirectURL},
+		"scope":                 {strings.Join(c.scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(document.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return document.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code for the user's tokens.
+func (c *Client) Exchange(ctx context.Context, code, verifier string) (*Tokens, error) {
+	document, err := c.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.redirectURL},
+		"code_verifier": {verifier},
+	}
+	if c.clientSecret == "" {
+		form.Set("client_id", c.clientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", document.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Add("Accept", "application/json")
+	if c.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.clientID), url.QueryEscape(c.clientSecret))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		providerErr := &Error{}
+		if json.Unmarshal(body, providerErr) != nil || providerErr.Code == "" {
+			return nil, fmt.Errorf("unexpected status %d exchanging authorization code", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("exchanging authorization code: %w", providerErr)
+	}
+
+	tokens := &Tokens{}
+	if err = json.Unmarshal(body, tokens); err != nil {
+		return nil, err
+	}
+	if tokens.IdToken == "" {
+		return nil, errors.New("provider did not return an ID token")
+	}
+	return tokens, nil
+}
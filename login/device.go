@@ -0,0 +1,95 @@
+package login
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	// DevicePath is the page where users enter the code shown by a device.
+	DevicePath = "/auth/device"
+	// DeviceCodeLifetime is how long a device has to be approved.
+	DeviceCodeLifetime = 10 * time.Minute
+	// DefaultPollInterval is how often devices may poll for their tokens, in seconds.
+	DefaultPollInterval = 5
+)
+
+// userCodeAlphabet leaves out vowels, to avoid spelling words, and characters that are easily confused.
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+type DeviceStatus string
+
+const (
+	DeviceStatusPending  DeviceStatus = "Pending"
+	DeviceStatusApproved DeviceStatus = "Approved"
+	DeviceStatusDenied   DeviceStatus = "Denied"
+)
+
+// DeviceAuthorization is a device waiting for its user to sign in from a browser (RFC 8628).
+type DeviceAuthorization struct {
+	DeviceCode string       `json:"deviceCode"`
+	UserCode   string       `json:"userCode"`
+	ClientId   string       `json:"clientId"`
+	Status     DeviceStatus `json:"status"`
+	// Interval is how often the device may poll at first, in seconds.
+	Interval  int       `json:"interval"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// Tokens are the user's tokens once approved.
+	Tokens *Tokens `json:"tokens,omitempty"`
+	// Username is who approved the device.
+	Username string `json:"username,omitempty"`
+}
+
+// UserCode finds the device a user code was issued to.
+type UserCode struct {
+	UserCode   string    `json:"userCode"`
+	DeviceCode string    `json:"deviceCode"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// DevicePoll is when a device last polled for its tokens and how often it may.
+type DevicePoll struct {
+	DeviceCode   string    `json:"deviceCode"`
+	Interval     int       `json:"interval"`
+	LastPolledAt time.Time `json:"lastPolledAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// Attempt is a sign-in that has been sent to the provider and is waiting for its callback.
+type Attempt struct {
+	State     string    `json:"state"`
+	Verifier  string    `json:"verifier"`
+	Nonce     string    `json:"nonce"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// UserCode is the device the sign-in approves. Sign-ins without one are for the browser.
+	UserCode string `json:"userCode,omitempty"`
+	// RedirectTo is where to send the browser after it has signed in.
+	RedirectTo string `json:"redirectTo,omitempty"`
+}
+
+// newUserCode returns a code like "BDFG-HJKL" for users to type in.
+func newUserCode() (string, error) {
+	code := make([]byte, 0, 9)
+	for i := 0; i < 8; i++ {
+		if i == 4 {
+			code = append(code, '-')
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(userCodeAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code = append(code, userCodeAlphabet[n.Int64()])
+	}
+	return string(code), nil
+}
+
+// normalizeUserCode accepts user codes typed in lower case or without the dash.
+func normalizeUserCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != 8 {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
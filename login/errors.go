@@ -0,0 +1,30 @@
+package login
+
+// Error is an OAuth 2.0 error response (RFC 6749 section 5.2, RFC 8628 section 3.5).
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+var (
+	ErrInvalidRequest       = &Error{Code: "invalid_request"}
+	ErrInvalidClient        = &Error{Code: "invalid_client"}
+	ErrInvalidGrant         = &Error{Code: "invalid_grant"}
+	ErrUnsupportedGrantType = &Error{Code: "unsupported_grant_type"}
+	ErrAuthorizationPending = &Error{Code: "authorization_pending"}
+	ErrSlowDown             = &Error{Code: "slow_down"}
+	ErrAccessDenied         = &Error{Code: "access_denied"}
+	ErrExpiredToken         = &Error{Code: "expired_token"}
+)
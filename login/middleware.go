@@ -0,0 +1,19 @@
+package login
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/ginctx"
+)
+
+var contextKey = ginctx.Key[*Service]("login")
+
+// Middleware makes the sign-in service available to the handlers for the request.
+func Middleware(service *Service) gin.HandlerFunc {
+	return contextKey.Middleware(service)
+}
+
+// FromContext returns the request's sign-in service, or nil if there is none.
+func FromContext(ctx *gin.Context) *Service {
+	service, _ := contextKey.Get(ctx)
+	return service
+}
@@ -0,0 +1,330 @@
+package login
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hunoz/maroon-api/authentication"
+	"github.com/hunoz/maroon-api/store"
+)
+
+// DefaultRedirect is where browsers go after signing in when they were not headed anywhere else.
+const DefaultRedirect = "/api/v1/self"
+
+// AttemptLifetime is how long a user has to sign in with the provider.
+const AttemptLifetime = 10 * time.Minute
+
+// DefaultDeviceClientIds are the clients allowed to use the device flow when Config.DeviceClientIds is not set.
+var DefaultDeviceClientIds = []string{"maroon-cli"}
+
+var ErrUnknownUserCode = errors.New("the code is not valid or has expired")
+
+// SignIn is a finished sign-in.
+type SignIn struct {
+	Attempt  *Attempt
+	Tokens   *Tokens
+	Identity *authentication.Identity
+	// ExpiresAt is when the ID token, and so a browser's session, expires.
+	ExpiresAt time.Time
+}
+
+// Config ...
+type Config struct {
+	// Issuer is the OIDC provider users sign in with. Its tokens must be trusted by Auth.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// BaseURL is where Maroon is served from, such as https://maroon.example.com.
+	BaseURL string
+	Scopes  []string
+	// DeviceClientIds are the client_id values devices may identify themselves with.
+	DeviceClientIds []string
+	// Auth validates the ID tokens the provider returns.
+	Auth    *authentication.Auth
+	Storage *Storage
+}
+
+// Service signs users in with the OIDC provider, either in the browser or on behalf of devices
+// that cannot open one.
+type Service struct {
+	client          *Client
+	baseURL         string
+	deviceClientIds []string
+	auth            *authentication.Auth
+	storage         *Storage
+	now             func() time.Time
+}
+
+func NewService(config *Config) *Service {
+	s := &Service{
+		client:          newClient(config),
+		baseURL:         config.BaseURL,
+		deviceClientIds: config.DeviceClientIds,
+		auth:            config.Auth,
+		storage:         config.Storage,
+		now:             time.Now,
+	}
+	if len(s.deviceClientIds) == 0 {
+		s.deviceClientIds = DefaultDeviceClientIds
+	}
+	if s.storage == nil {
+		s.storage = NewMemoryStorage()
+	}
+	return s
+}
+
+// VerificationURI is the page users enter their device's user code on.
+func (s *Service) VerificationURI() string {
+	return s.baseURL + DevicePath
+}
+
+// AuthorizeDevice starts the device flow for a client (RFC 8628 section 3.1).
+func (s *Service) AuthorizeDevice(clientId string) (*DeviceAuthorization, error) {
+	if !contains(s.deviceClientIds, clientId) {
+		return nil, ErrInvalidClient
+	}
+
+	deviceCode, err := randomString(32)
+	if err != nil {
+		return nil, err
+	}
+	userCode, err := newUserCode()
+	if err != nil {
+		return nil, err
+	}
+
+	device := &DeviceAuthorization{
+		DeviceCode: deviceCode,
+		UserCode:   userCode,
+		ClientId:   clientId,
+		Status:     DeviceStatusPending,
+		Interval:   DefaultPollInterval,
+		ExpiresAt:  s.now().Add(DeviceCodeLifetime),
+	}
+	if err = s.storage.Devices.Put(device); err != nil {
+		return nil, err
+	}
+	if err = s.storage.UserCodes.Put(&UserCode{UserCode: userCode, DeviceCode: deviceCode, ExpiresAt: device.ExpiresAt}); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// PendingDevice returns the device waiting to be approved with the user code, so that the user
+// can check it is theirs before signing in.
+func (s *Service) PendingDevice(userCode string) (*DeviceAuthorization, error) {
+	code, err := s.storage.UserCodes.Get(normalizeUserCode(userCode))
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUnknownUserCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	device, err := s.storage.Devices.Get(code.DeviceCode)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrUnknownUserCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	if device.Status != DeviceStatusPending || !s.now().Before(device.ExpiresAt) {
+		return nil, ErrUnknownUserCode
+	}
+	return device, nil
+}
+
+// BeginDeviceLogin returns where to send the browser to sign in and approve the device with
+// the user code. The user must have confirmed that the device is theirs.
+func (s *Service) BeginDeviceLogin(userCode string) (string, error) {
+	device, err := s.PendingDevice(userCode)
+	if err != nil {
+		return "", err
+	}
+
+	return s.begin(&Attempt{UserCode: device.UserCode})
+}
+
+// DenyDevice denies the device with the user code, for users who did not start its sign-in.
+func (s *Service) DenyDevice(userCode string) error {
+	return s.decideDevice(normalizeUserCode(userCode), DeviceStatusDenied, nil, "")
+}
+
+// BeginLogin returns where to send the browser to sign in. Afterwards the browser is sent on
+// to redirectTo if it is a path on Maroon, or to DefaultRedirect.
+func (s *Service) BeginLogin(redirectTo string) (string, error) {
+	if !strings.HasPrefix(redirectTo, "/") || strings.HasPrefix(redirectTo, "//") || strings.HasPrefix(redirectTo, "/\\") {
+		redirectTo = DefaultRedirect
+	}
+	return s.begin(&Attempt{RedirectTo: redirectTo})
+}
+
+// Session returns the value of a session cookie for a browser sign-in.
+func (s *Service) Session(signIn *SignIn) (string, error) {
+	return s.auth.SealSession(signIn.Tokens.IdToken)
+}
+
+// SecureCookies reports whether Maroon is served over HTTPS, so cookies should only be sent over it.
+func (s *Service) SecureCookies() bool {
+	return strings.HasPrefix(s.baseURL, "https://")
+}
+
+// begin saves the attempt with a new state, nonce and PKCE verifier, and returns the provider's sign-in URL.
+func (s *Service) begin(attempt *Attempt) (string, error) {
+	var err error
+	if attempt.State, err = randomString(32); err != nil {
+		return "", err
+	}
+	if attempt.Verifier, err = randomString(32); err != nil {
+		return "", err
+	}
+	if attempt.Nonce, err = randomString(16); err != nil {
+		return "", err
+	}
+	attempt.ExpiresAt = s.now().Add(AttemptLifetime)
+
+	url, err := s.client.AuthCodeURL(attempt.State, attempt.Verifier, attempt.Nonce)
+	if err != nil {
+		return "", err
+	}
+	if err = s.storage.Attempts.Put(attempt); err != nil {
+		return "", err
+	}
+	return url, nil
+}
+
+// Callback finishes a sign-in when the provider redirects back with code and state, or with
+// providerError if the user did not sign in. Device sign-ins approve or deny the device.
+func (s *Service) Callback(ctx context.Context, state, code, providerError string) (*SignIn, error) {
+	attempt, err := s.storage.Attempts.Take(state)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown or expired sign-in", ErrInvalidRequest)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if providerError != "" {
+		if attempt.UserCode != "" {
+			err = s.decideDevice(attempt.UserCode, DeviceStatusDenied, nil, "")
+		}
+		return nil, errors.Join(&Error{Code: providerError}, err)
+	}
+
+	tokens, err := s.client.Exchange(ctx, code, attempt.Verifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.auth.ParseJWT(tokens.IdToken)
+	if err != nil {
+		return nil, err
+	}
+	if nonce, _ := claims["nonce"].(string); nonce != attempt.Nonce {
+		return nil, fmt.Errorf("%w: ID token nonce does not match", ErrInvalidGrant)
+	}
+	identity := s.auth.Issuer(claims["iss"].(string)).Identity(claims)
+	exp, _ := claims["exp"].(float64)
+
+	if attempt.UserCode != "" {
+		if err = s.decideDevice(attempt.UserCode, DeviceStatusApproved, tokens, identity.Username); err != nil {
+			return nil, err
+		}
+	}
+
+	return &SignIn{Attempt: attempt, Tokens: tokens, Identity: identity, ExpiresAt: time.Unix(int64(exp), 0)}, nil
+}
+
+// decideDevice approves or denies a device. Its user code is taken first, so that a device is
+// only ever decided once.
+func (s *Service) decideDevice(userCode string, status DeviceStatus, tokens *Tokens, username string) error {
+	code, err := s.storage.UserCodes.Take(userCode)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrUnknownUserCode
+	}
+	if err != nil {
+		return err
+	}
+
+	device, err := s.storage.Devices.Get(code.DeviceCode)
+	if errors.Is(err, store.ErrNotFound) {
+		return ErrUnknownUserCode
+	}
+	if err != nil {
+		return err
+	}
+	if device.Status != DeviceStatusPending || !s.now().Before(device.ExpiresAt) {
+		return ErrUnknownUserCode
+	}
+
+	device.Status = status
+	device.Tokens = tokens
+	device.Username = username
+	return s.storage.Devices.Put(device)
+}
+
+// PollDevice returns the user's tokens once the device has been approved (RFC 8628 section 3.4).
+// The tokens are only returned once.
+func (s *Service) PollDevice(deviceCode, clientId string) (*DeviceAuthorization, error) {
+	device, err := s.storage.Devices.Get(deviceCode)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrInvalidGrant
+	}
+	if err != nil {
+		return nil, err
+	}
+	if device.ClientId != clientId {
+		return nil, ErrInvalidGrant
+	}
+
+	now := s.now()
+	if !now.Before(device.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+
+	switch device.Status {
+	case DeviceStatusApproved:
+		// Taking the device means only one poll ever gets the tokens.
+		device, err = s.storage.Devices.Take(deviceCode)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrInvalidGrant
+		}
+		return device, err
+	case DeviceStatusDenied:
+		if err = s.storage.Devices.Delete(deviceCode); errors.Is(err, store.ErrNotFound) {
+			err = nil
+		}
+		return nil, errors.Join(ErrAccessDenied, err)
+	}
+
+	poll, err := s.storage.Polls.Get(deviceCode)
+	if errors.Is(err, store.ErrNotFound) {
+		poll = &DevicePoll{DeviceCode: deviceCode, Interval: device.Interval, ExpiresAt: device.ExpiresAt}
+	} else if err != nil {
+		return nil, err
+	}
+
+	tooSoon := now.Sub(poll.LastPolledAt) < time.Duration(poll.Interval)*time.Second
+	poll.LastPolledAt = now
+	if tooSoon {
+		poll.Interval += DefaultPollInterval
+	}
+	if err = s.storage.Polls.Put(poll); err != nil {
+		return nil, err
+	}
+	if tooSoon {
+		return nil, ErrSlowDown
+	}
+	return nil, ErrAuthorizationPending
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
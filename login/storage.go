@@ -0,0 +1,79 @@
+package login
+
+import (
+	"time"
+
+	"github.com/hunoz/maroon-api/store"
+)
+
+// Storage holds device authorizations and sign-in attempts until they are finished or expire.
+// They hold users' tokens, so there is deliberately no file backend; a shared backend must be
+// encrypted at rest.
+type Storage struct {
+	Devices store.Store[DeviceAuthorization]
+	// UserCodes find devices by the code users type in. A user code is taken when the device is
+	// approved or denied, so each device is only ever decided once.
+	UserCodes store.Store[UserCode]
+	// Polls are kept apart from the devices so that polling never overwrites an approval.
+	Polls    store.Store[DevicePoll]
+	Attempts store.Store[Attempt]
+}
+
+func NewMemoryStorage() *Storage {
+	return &Storage{
+		Devices:   store.NewMemory[DeviceAuthorization](),
+		UserCodes: store.NewMemory[UserCode](),
+		Polls:     store.NewMemory[DevicePoll](),
+		Attempts:  store.NewMemory[Attempt](),
+	}
+}
+
+func (d DeviceAuthorization) StoreKey() string {
+	return d.DeviceCode
+}
+
+func (d DeviceAuthorization) StoreOrder() time.Time {
+	return d.ExpiresAt
+}
+
+// StoreExpiry keeps devices for a while after they expire, so that devices polling late can be
+// told their code has expired.
+func (d DeviceAuthorization) StoreExpiry() time.Time {
+	return d.ExpiresAt.Add(DeviceCodeLifetime)
+}
+
+func (u UserCode) StoreKey() string {
+	return u.UserCode
+}
+
+func (u UserCode) StoreOrder() time.Time {
+	return u.ExpiresAt
+}
+
+func (u UserCode) StoreExpiry() time.Time {
+	return u.ExpiresAt
+}
+
+func (p DevicePoll) StoreKey() string {
+	return p.DeviceCode
+}
+
+func (p DevicePoll) StoreOrder() time.Time {
+	return p.LastPolledAt
+}
+
+func (p DevicePoll) StoreExpiry() time.Time {
+	return p.ExpiresAt.Add(DeviceCodeLifetime)
+}
+
+func (a Attempt) StoreKey() string {
+	return a.State
+}
+
+func (a Attempt) StoreOrder() time.Time {
+	return a.ExpiresAt
+}
+
+func (a Attempt) StoreExpiry() time.Time {
+	return a.ExpiresAt
+}
@@ -2,16 +2,32 @@ package main
 
 import (
 	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	ginadapter "github.com/awslabs/aws-lambda-go-api-proxy/gin"
 	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/api/oauth"
 	v1 "github.com/hunoz/maroon-api/api/v1"
+	"github.com/hunoz/maroon-api/apitoken"
 	"github.com/hunoz/maroon-api/authentication"
+	"github.com/hunoz/maroon-api/authorization"
+	"github.com/hunoz/maroon-api/elevation"
+	"github.com/hunoz/maroon-api/federation"
 	"github.com/hunoz/maroon-api/logging"
+	"github.com/hunoz/maroon-api/login"
+	"github.com/hunoz/maroon-api/revocation"
+	"github.com/hunoz/maroon-api/signing"
+	"github.com/hunoz/maroon-api/store"
 	"github.com/sirupsen/logrus"
 )
 
@@ -43,7 +59,7 @@ func getRegionAndPoolId() (region string, poolId string) {
 	var cognitoRegion string
 	var cognitoPoolId string
 	if cognitoRegion = os.Getenv("COGNITO_REGION"); cognitoRegion == "" {
-		logrus.Fatal("'COGNITO_REGION' environment variable not set!")
+		logrus.Fatal("Neither 'OIDC_ISSUER' nor 'COGNITO_REGION' environment variable set!")
 		os.Exit(1)
 	}
 	if cognitoPoolId = os.Getenv("COGNITO_POOL_ID"); cognitoPoolId == "" {
@@ -54,26 +70,434 @@ func getRegionAndPoolId() (region string, poolId string) {
 	return cognitoRegion, cognitoPoolId
 }
 
-func setupRoutes() {
-	cognitoRegion, cognitoPoolId := getRegionAndPoolId()
+func getAuthorizer(defaultIssuer string) authorization.Authorizer {
+	refreshInterval := 5 * time.Minute
+	if rawInterval := os.Getenv("POLICY_REFRESH_INTERVAL"); rawInterval != "" {
+		interval, err := time.ParseDuration(rawInterval)
+		if err != nil {
+			logrus.Fatalf("Error parsing 'POLICY_REFRESH_INTERVAL': %s", err.Error())
+		}
+		if interval <= 0 {
+			logrus.Fatalf("Error parsing 'POLICY_REFRESH_INTERVAL': %s is not positive", interval)
+		}
+		refreshInterval = interval
+	}
+
+	var source authorization.Source
+	if policyFile := os.Getenv("POLICY_FILE"); policyFile != "" {
+		source = &authorization.FileSource{Path: policyFile}
+	} else if policySecretId := os.Getenv("POLICY_SECRET_ID"); policySecretId != "" {
+		secretSource, err := authorization.NewSecretsManagerSource(policySecretId)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		source = secretSource
+	} else {
+		logrus.Warn("Neither 'POLICY_FILE' nor 'POLICY_SECRET_ID' environment variable set, all credential requests will be denied")
+		return authorization.NewPolicy(nil)
+	}
+
+	store, err := authorization.NewStore(source, refreshInterval, defaultIssuer)
+	if err != nil {
+		logrus.Fatalf("Error loading policy: %s", err.Error())
+	}
 
-	auth := authentication.NewAuth(&authentication.Config{
-		CognitoRegion:     cognitoRegion,
-		CognitoUserPoolID: cognitoPoolId,
-	})
+	return store
+}
+
+func getElevationService(defaultIssuer string) *elevation.Service {
+	config := &elevation.Config{ApproverIssuer: os.Getenv("ELEVATION_APPROVER_ISSUER")}
+	if config.ApproverIssuer == "" {
+		config.ApproverIssuer = defaultIssuer
+	}
+	if approverGroups := os.Getenv("ELEVATION_APPROVER_GROUPS"); approverGroups != "" {
+		config.ApproverGroups = strings.Split(approverGroups, ",")
+	} else {
+		logrus.Warn("'ELEVATION_APPROVER_GROUPS' environment variable not set, access requests cannot be approved")
+	}
+	if rawMaxDuration := os.Getenv("ELEVATION_MAX_DURATION"); rawMaxDuration != "" {
+		maxDuration, err := time.ParseDuration(rawMaxDuration)
+		if err != nil {
+			logrus.Fatalf("Error parsing 'ELEVATION_MAX_DURATION': %s", err.Error())
+		}
+		config.MaxDuration = maxDuration
+	}
+	if storage := getSharedStorage[elevation.Request]("accessRequest"); storage != nil {
+		config.Storage = storage
+	} else if storageFile := os.Getenv("ELEVATION_STORAGE_FILE"); storageFile != "" {
+		storage, err := store.NewFile[elevation.Request](storageFile)
+		if err != nil {
+			logrus.Fatalf("Error loading access requests: %s", err.Error())
+		}
+		config.Storage = storage
+	}
+
+	return elevation.NewService(config)
+}
+
+// issuerSettings is an issuer in 'OIDC_ISSUERS', which may name a secret holding its audiences.
+type issuerSettings struct {
+	authentication.IssuerConfig
+	AudiencesSecretId string `json:"audiencesSecretId"`
+}
+
+func getIssuerSettings() []issuerSettings {
+	if rawIssuers := os.Getenv("OIDC_ISSUERS"); rawIssuers != "" {
+		var issuers []issuerSettings
+		if err := json.Unmarshal([]byte(rawIssuers), &issuers); err != nil {
+			logrus.Fatalf("Error parsing 'OIDC_ISSUERS': %s", err.Error())
+		}
+		return issuers
+	}
+
+	issuer := issuerSettings{
+		IssuerConfig: authentication.IssuerConfig{
+			Issuer:   os.Getenv("OIDC_ISSUER"),
+			JWKSURL:  os.Getenv("OIDC_JWKS_URL"),
+			JWKSFile: os.Getenv("OIDC_JWKS_FILE"),
+			JWKS:     json.RawMessage(os.Getenv("OIDC_JWKS")),
+		},
+		AudiencesSecretId: os.Getenv("AUDIENCES_SECRET_ID"),
+	}
+	if issuer.Issuer == "" {
+		issuer.CognitoRegion, issuer.CognitoUserPoolID = getRegionAndPoolId()
+	}
+	if tokenUses := os.Getenv("JWT_TOKEN_USES"); tokenUses != "" {
+		issuer.TokenUses = strings.Split(tokenUses, ",")
+	}
+	if rawClaimMapping := os.Getenv("OIDC_CLAIM_MAPPING"); rawClaimMapping != "" {
+		if err := json.Unmarshal([]byte(rawClaimMapping), &issuer.ClaimMapping); err != nil {
+			logrus.Fatalf("Error parsing 'OIDC_CLAIM_MAPPING': %s", err.Error())
+		}
+	}
+
+	return []issuerSettings{issuer}
+}
+
+func getAuthConfig() *authentication.Config {
+	config := &authentication.Config{
+		ClockSkew: time.Minute,
+	}
+
+	for _, issuer := range getIssuerSettings() {
+		if issuer.AudiencesSecretId != "" {
+			audiences, err := authentication.LoadAudiences(issuer.AudiencesSecretId)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+			issuer.Audiences = append(issuer.Audiences, audiences...)
+		}
+		if issuer.Issuer == "" {
+			issuer.Issuer = authentication.CognitoIssuer(issuer.CognitoRegion, issuer.CognitoUserPoolID)
+		}
+		if len(issuer.Audiences) == 0 {
+			logrus.Warnf("No audiences set for issuer '%s', tokens for any audience will be accepted", issuer.Issuer)
+		}
+		config.Issuers = append(config.Issuers, issuer.IssuerConfig)
+	}
+
+	if rawTokenCacheSize := os.Getenv("JWT_TOKEN_CACHE_SIZE"); rawTokenCacheSize != "" {
+		tokenCacheSize, err := strconv.Atoi(rawTokenCacheSize)
+		if err != nil {
+			logrus.Fatalf("Error parsing 'JWT_TOKEN_CACHE_SIZE': %s", err.Error())
+		}
+		config.TokenCacheSize = tokenCacheSize
+	}
+	if rawSessionKey := os.Getenv("SESSION_KEY"); rawSessionKey != "" {
+		sessionKey, err := base64.StdEncoding.DecodeString(rawSessionKey)
+		if err != nil {
+			logrus.Fatalf("Error parsing 'SESSION_KEY': %s", err.Error())
+		}
+		config.SessionKey = sessionKey
+	} else if secretId := os.Getenv("SESSION_KEY_SECRET_ID"); secretId != "" {
+		sessionKey, err := authentication.LoadSessionKey(secretId)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		config.SessionKey = sessionKey
+	}
+	if rawClockSkew := os.Getenv("JWT_CLOCK_SKEW"); rawClockSkew != "" {
+		clockSkew, err := time.ParseDuration(rawClockSkew)
+		if err != nil {
+			logrus.Fatalf("Error parsing 'JWT_CLOCK_SKEW': %s", err.Error())
+		}
+		config.ClockSkew = clockSkew
+	}
+
+	return config
+}
+
+// getSharedStorage returns a store for one kind of item in the DynamoDB table named by
+// 'STORAGE_TABLE', or nil if it is not set. Without it, Lambda instances don't see each
+// other's items.
+func getSharedStorage[T store.Item](kind string) store.Store[T] {
+	table := os.Getenv("STORAGE_TABLE")
+	if table == "" {
+		if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+			logrus.Warnf("'STORAGE_TABLE' environment variable not set, %s items are only known to this instance", kind)
+		}
+		return nil
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		logrus.Fatalf("Error creating config: %s", err.Error())
+	}
+	return store.NewDynamoDB[T](dynamodb.NewFromConfig(cfg), table, kind)
+}
+
+func getRevocationService(apiTokenService *apitoken.Service) *revocation.Service {
+	config := &revocation.Config{MaxTokenLifetime: revocation.DefaultMaxTokenLifetime}
+	if rawMaxTokenLifetime := os.Getenv("REVOCATION_MAX_TOKEN_LIFETIME"); rawMaxTokenLifetime != "" {
+		maxTokenLifetime, err := time.ParseDuration(rawMaxTokenLifetime)
+		if err != nil {
+			logrus.Fatalf("Error parsing 'REVOCATION_MAX_TOKEN_LIFETIME': %s", err.Error())
+		}
+		config.MaxTokenLifetime = maxTokenLifetime
+	}
+	// Subjects can be API tokens too, which may last longer than tokens from other issuers.
+	if apiTokenService != nil && apiTokenService.MaxDuration() > config.MaxTokenLifetime {
+		config.MaxTokenLifetime = apiTokenService.MaxDuration()
+	}
+
+	if storage := getSharedStorage[revocation.Entry]("revocation"); storage != nil {
+		config.Storage = storage
+	} else if storageFile := os.Getenv("REVOCATION_STORAGE_FILE"); storageFile != "" {
+		storage, err := store.NewFile[revocation.Entry](storageFile)
+		if err != nil {
+			logrus.Fatalf("Error loading revocations: %s", err.Error())
+		}
+		config.Storage = storage
+	}
+
+	return revocation.NewService(config)
+}
+
+// getSigner returns the signer for Maroon's own tokens, or nil if no signing key is configured.
+func getSigner() *signing.Signer {
+	var key *rsa.PrivateKey
+	var err error
+	if keyFile := os.Getenv("MAROON_SIGNING_KEY_FILE"); keyFile != "" {
+		key, err = signing.LoadPrivateKeyFile(keyFile)
+	} else if secretId := os.Getenv("MAROON_SIGNING_KEY_SECRET_ID"); secretId != "" {
+		key, err = signing.LoadPrivateKeySecret(secretId)
+	} else {
+		logrus.Warn("Neither 'MAROON_SIGNING_KEY_FILE' nor 'MAROON_SIGNING_KEY_SECRET_ID' environment variable set, Maroon cannot issue tokens")
+		return nil
+	}
+	if err != nil {
+		logrus.Fatalf("Error loading signing key: %s", err.Error())
+	}
+
+	issuer := os.Getenv("MAROON_ISSUER")
+	if issuer == "" {
+		issuer = "maroon-api"
+	}
+	signer, err := signing.NewSigner(issuer, key)
+	if err != nil {
+		logrus.Fatalf("Error creating signer: %s", err.Error())
+	}
+	return signer
+}
+
+// getApiTokenService returns nil if Maroon cannot sign tokens.
+func getApiTokenService(signer *signing.Signer) *apitoken.Service {
+	if signer == nil {
+		return nil
+	}
+
+	config := &apitoken.Config{Signer: signer}
+	if rawMaxDuration := os.Getenv("API_TOKEN_MAX_DURATION"); rawMaxDuration != "" {
+		maxDuration, err := time.ParseDuration(rawMaxDuration)
+		if err != nil {
+			logrus.Fatalf("Error parsing 'API_TOKEN_MAX_DURATION': %s", err.Error())
+		}
+		config.MaxDuration = maxDuration
+	}
+	if storage := getSharedStorage[apitoken.Token]("apiToken"); storage != nil {
+		config.Storage = storage
+	} else if storageFile := os.Getenv("API_TOKEN_STORAGE_FILE"); storageFile != "" {
+		storage, err := store.NewFile[apitoken.Token](storageFile)
+		if err != nil {
+			logrus.Fatalf("Error loading API tokens: %s", err.Error())
+		}
+		config.Storage = storage
+	}
+
+	return apitoken.NewService(config)
+}
+
+// getLoginService returns nil unless an OIDC client is configured for signing users in.
+func getLoginService(auth *authentication.Auth, issuers []authentication.IssuerConfig) *login.Service {
+	clientId := os.Getenv("OIDC_CLIENT_ID")
+	if clientId == "" {
+		logrus.Warn("'OIDC_CLIENT_ID' environment variable not set, sign-in endpoints are disabled")
+		return nil
+	}
+	baseURL := os.Getenv("MAROON_BASE_URL")
+	if baseURL == "" {
+		logrus.Fatal("'MAROON_BASE_URL' environment variable must be set to sign users in")
+	}
+
+	config := &login.Config{
+		Issuer:       os.Getenv("OIDC_LOGIN_ISSUER"),
+		ClientID:     clientId,
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		BaseURL:      strings.TrimSuffix(baseURL, "/"),
+		Auth:         auth,
+	}
+	if config.Issuer == "" {
+		// Sign users in with the first issuer of user tokens
+		for _, issuer := range issuers {
+			if issuer.Preset == "" && issuer.PrincipalType != authentication.PrincipalTypeMachine {
+				config.Issuer = issuer.Issuer
+				break
+			}
+		}
+	}
+	if config.Issuer == "" {
+		logrus.Fatal("'OIDC_LOGIN_ISSUER' environment variable must be set when no issuer of user tokens is configured")
+	}
+	if scopes := os.Getenv("OIDC_SCOPES"); scopes != "" {
+		config.Scopes = strings.Split(scopes, " ")
+	}
+	if clientIds := os.Getenv("DEVICE_CLIENT_IDS"); clientIds != "" {
+		config.DeviceClientIds = strings.Split(clientIds, ",")
+	}
+	if devices := getSharedStorage[login.DeviceAuthorization]("loginDevice"); devices != nil {
+		config.Storage = &login.Storage{
+			Devices:   devices,
+			UserCodes: getSharedStorage[login.UserCode]("loginUserCode"),
+			Polls:     getSharedStorage[login.DevicePoll]("loginDevicePoll"),
+			Attempts:  getSharedStorage[login.Attempt]("loginAttempt"),
+		}
+	}
+
+	return login.NewService(config)
+}
+
+func getBroker(signer *signing.Signer) *federation.Broker {
+	config := &federation.Config{
+		Source:             federation.Source(os.Getenv("SOURCE_CREDENTIALS")),
+		SecretId:           os.Getenv("IAM_USER_SECRET_ID"),
+		WebIdentityRoleArn: os.Getenv("WEB_IDENTITY_ROLE_ARN"),
+		Signer:             signer,
+
+		RoleSessionNameTemplate: os.Getenv("ROLE_SESSION_NAME_TEMPLATE"),
+	}
+	if rawCredentialsTTL := os.Getenv("IAM_CREDENTIALS_TTL"); rawCredentialsTTL != "" {
+		credentialsTTL, err := time.ParseDuration(rawCredentialsTTL)
+		if err != nil {
+			logrus.Fatalf("Error parsing 'IAM_CREDENTIALS_TTL': %s", err.Error())
+		}
+		config.CredentialsTTL = credentialsTTL
+	}
+	if rawChains := os.Getenv("ROLE_CHAINS"); rawChains != "" {
+		if err := json.Unmarshal([]byte(rawChains), &config.Chains); err != nil {
+			logrus.Fatalf("Error parsing 'ROLE_CHAINS': %s", err.Error())
+		}
+	}
+	if rawSessionTags := os.Getenv("SESSION_TAGS"); rawSessionTags != "" {
+		if err := json.Unmarshal([]byte(rawSessionTags), &config.SessionTags); err != nil {
+			logrus.Fatalf("Error parsing 'SESSION_TAGS': %s", err.Error())
+		}
+	}
+	if rawDisableSourceIdentity := os.Getenv("DISABLE_SOURCE_IDENTITY"); rawDisableSourceIdentity != "" {
+		disableSourceIdentity, err := strconv.ParseBool(rawDisableSourceIdentity)
+		if err != nil {
+			logrus.Fatalf("Error parsing 'DISABLE_SOURCE_IDENTITY': %s", err.Error())
+		}
+		config.DisableSourceIdentity = disableSourceIdentity
+	}
+
+	broker, err := federation.NewBroker(context.Background(), config)
+	if err != nil {
+		logrus.Fatalf("Error configuring role broker: %s", err.Error())
+	}
+	return broker
+}
+
+func setupRoutes() {
+	signer := getSigner()
+	apiTokenService := getApiTokenService(signer)
+	revocationService := getRevocationService(apiTokenService)
+	authConfig := getAuthConfig()
+	authConfig.Denylists = append(authConfig.Denylists, revocationService)
+	if apiTokenService != nil {
+		issuerConfig, err := apiTokenService.IssuerConfig()
+		if err != nil {
+			logrus.Fatalf("Error configuring API tokens: %s", err.Error())
+		}
+		authConfig.Issuers = append(authConfig.Issuers, issuerConfig)
+		authConfig.Denylists = append(authConfig.Denylists, apiTokenService)
+	}
+	auth, err := authentication.NewAuth(context.Background(), authConfig)
+	if err != nil {
+		logrus.Fatalf("Error configuring authentication: %s", err.Error())
+	}
 
 	router := gin.New()
 	router.Use(logging.JSONLogMiddleware(stage))
 	router.Use(gin.Recovery())
-	router.Use(authentication.JWTMiddleware(*auth))
+
+	if loginService := getLoginService(auth, authConfig.Issuers); loginService != nil {
+		authRoutes := router.Group("/auth")
+		authRoutes.Use(login.Middleware(loginService))
+		authRoutes.POST("/device/code", oauth.AuthorizeDevice)
+		authRoutes.POST("/device/token", oauth.DeviceToken)
+		authRoutes.GET("/device", oauth.VerifyDevice)
+		authRoutes.POST("/device", oauth.ConfirmDevice)
+		authRoutes.GET("/callback", oauth.Callback)
+		if auth.SessionsEnabled() {
+			authRoutes.GET("/login", oauth.Login)
+			authRoutes.GET("/logout", oauth.ConfirmLogout)
+			authRoutes.POST("/logout", oauth.Logout)
+		} else {
+			logrus.Warn("Neither 'SESSION_KEY' nor 'SESSION_KEY_SECRET_ID' environment variable set, browser sign-in is disabled")
+		}
+	}
+
+	if signer != nil {
+		wellKnown := router.Group("/.well-known")
+		wellKnown.Use(signing.Middleware(signer))
+		wellKnown.GET("/openid-configuration", oauth.OpenIDConfiguration)
+		wellKnown.GET("/jwks.json", oauth.JWKS)
+	}
 
 	api := router.Group("/api")
+	api.Use(v1.AuthenticationErrorMiddleware())
+	api.Use(authentication.JWTMiddleware(*auth))
+	elevationService := getElevationService(auth.DefaultIssuer())
+	api.Use(elevation.Middleware(elevationService))
+	api.Use(revocation.Middleware(revocationService))
+	api.Use(apitoken.Middleware(apiTokenService))
+	api.Use(federation.Middleware(getBroker(signer)))
+	api.Use(authorization.Middleware(apitoken.NewAuthorizer(elevation.NewAuthorizer(getAuthorizer(auth.DefaultIssuer()), elevationService))))
 
 	v1Api := api.Group("/v1")
 
 	v1Api.GET("/console-url", v1.GetConsoleUrl)
 	v1Api.GET("/assume-role", v1.AssumeRole)
 	v1Api.GET("/self", v1.GetUserInfo)
+	v1Api.GET("/authz/explain", v1.ExplainAuthorization)
+
+	accessRequests := v1Api.Group("/access-requests")
+	accessRequests.POST("", v1.CreateAccessRequest)
+	accessRequests.GET("", v1.ListAccessRequests)
+	accessRequests.GET("/:id", v1.GetAccessRequest)
+	accessRequests.POST("/:id/approve", v1.ApproveAccessRequest)
+	accessRequests.POST("/:id/deny", v1.DenyAccessRequest)
+
+	revocations := v1Api.Group("/revocations")
+	revocations.POST("", v1.CreateRevocation)
+	revocations.GET("", v1.ListRevocations)
+	revocations.DELETE("/:id", v1.DeleteRevocation)
+
+	apiTokens := v1Api.Group("/api-tokens")
+	apiTokens.POST("", v1.CreateApiToken)
+	apiTokens.GET("", v1.ListApiTokens)
+	apiTokens.GET("/:id", v1.GetApiToken)
+	apiTokens.DELETE("/:id", v1.RevokeApiToken)
 
 	ginRouter = router
 }
@@ -0,0 +1,69 @@
+package revocation
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hunoz/maroon-api/store"
+)
+
+var (
+	ErrNotFound     = store.ErrNotFound
+	ErrInvalidEntry = errors.New("a revocation must name exactly one of a token ID or a subject")
+)
+
+// Entry revokes either a single token by its jti claim, or the tokens of a subject. A subject
+// entry with IssuedBefore set only revokes the tokens issued before then, so the user can
+// sign in again afterwards.
+type Entry struct {
+	Id string `json:"id"`
+	// Issuer limits the entry to tokens from one issuer. Empty matches every issuer.
+	Issuer       string    `json:"issuer,omitempty"`
+	TokenId      string    `json:"tokenId,omitempty"`
+	Subject      string    `json:"subject,omitempty"`
+	IssuedBefore time.Time `json:"issuedBefore"`
+	Reason       string    `json:"reason"`
+	CreatedBy    string    `json:"createdBy"`
+	CreatedAt    time.Time `json:"createdAt"`
+	// ExpiresAt is when every token the entry revokes has expired. It is only set for entries
+	// with IssuedBefore; others are kept until they are deleted.
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (e Entry) StoreKey() string {
+	return e.Id
+}
+
+func (e Entry) StoreOrder() time.Time {
+	return e.CreatedAt
+}
+
+func (e Entry) StoreExpiry() time.Time {
+	return e.ExpiresAt
+}
+
+// Validate checks that the entry describes tokens to revoke.
+func (e *Entry) Validate() error {
+	if (e.TokenId == "") == (e.Subject == "") {
+		return ErrInvalidEntry
+	}
+	if e.TokenId != "" && !e.IssuedBefore.IsZero() {
+		return ErrInvalidEntry
+	}
+	return nil
+}
+
+// Matches reports whether the entry revokes a token with the given claims. A token without an
+// iat claim is treated as issued before any time.
+func (e *Entry) Matches(issuer, tokenId, subject string, issuedAt time.Time) bool {
+	if e.Issuer != "" && e.Issuer != issuer {
+		return false
+	}
+	if e.TokenId != "" {
+		return e.TokenId == tokenId
+	}
+	if e.Subject != subject {
+		return false
+	}
+	return e.IssuedBefore.IsZero() || issuedAt.Before(e.IssuedBefore)
+}
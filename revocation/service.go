@@ -0,0 +1,127 @@
+package revocation
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hunoz/maroon-api/store"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMaxTokenLifetime is how long accepted tokens last at most when
+	// Config.MaxTokenLifetime is not set, such as Cognito's one day.
+	DefaultMaxTokenLifetime = 24 * time.Hour
+	// cacheTTL is how long entries read from storage are used for, so that every request does
+	// not read all of them. Entries made or deleted on this instance apply straight away.
+	cacheTTL = 10 * time.Second
+)
+
+type Config struct {
+	Storage Storage
+	// MaxTokenLifetime is the longest time between a token's iat and exp claims. Entries with
+	// IssuedBefore are dropped once every token they revoke has expired.
+	MaxTokenLifetime time.Duration
+}
+
+// Service records revocations and checks tokens against them.
+type Service struct {
+	storage          Storage
+	maxTokenLifetime time.Duration
+	now              func() time.Time
+
+	mu       sync.Mutex
+	entries  []*Entry
+	loadedAt time.Time
+}
+
+// NewService returns a service backed by config.Storage, or by memory if it is nil.
+func NewService(config *Config) *Service {
+	s := &Service{
+		storage:          config.Storage,
+		maxTokenLifetime: config.MaxTokenLifetime,
+		now:              time.Now,
+	}
+	if s.storage == nil {
+		s.storage = store.NewMemory[Entry]()
+	}
+	if s.maxTokenLifetime == 0 {
+		s.maxTokenLifetime = DefaultMaxTokenLifetime
+	}
+	return s
+}
+
+// Revoke validates and saves a new entry on behalf of createdBy.
+func (s *Service) Revoke(entry Entry, createdBy string) (*Entry, error) {
+	if err := entry.Validate(); err != nil {
+		return nil, err
+	}
+
+	id, err := store.NewId()
+	if err != nil {
+		return nil, err
+	}
+	entry.Id = id
+	entry.CreatedBy = createdBy
+	entry.CreatedAt = s.now().UTC()
+	if !entry.IssuedBefore.IsZero() {
+		entry.ExpiresAt = entry.IssuedBefore.Add(s.maxTokenLifetime)
+	}
+
+	defer s.invalidate()
+	if err = s.storage.Put(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Delete removes an entry, which makes the tokens it revoked valid again.
+func (s *Service) Delete(id string) error {
+	defer s.invalidate()
+	return s.storage.Delete(id)
+}
+
+// List returns every entry.
+func (s *Service) List() ([]*Entry, error) {
+	return s.storage.List()
+}
+
+// IsRevoked reports whether a token with the given claims has been revoked. Tokens are
+// treated as revoked if the entries cannot be read.
+func (s *Service) IsRevoked(issuer, tokenId, subject string, issuedAt time.Time) bool {
+	entries, err := s.cachedEntries()
+	if err != nil {
+		logrus.Errorf("Error reading revocations, rejecting token: %s", err.Error())
+		return true
+	}
+
+	for _, entry := range entries {
+		if entry.Matches(issuer, tokenId, subject, issuedAt) {
+			return true
+		}
+	}
+	return false
+}
+
+// cachedEntries returns the entries, reading them again once they are older than cacheTTL.
+func (s *Service) cachedEntries() ([]*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.loadedAt.IsZero() && s.now().Sub(s.loadedAt) < cacheTTL {
+		return s.entries, nil
+	}
+
+	entries, err := s.storage.List()
+	if err != nil {
+		return nil, err
+	}
+	s.entries = entries
+	s.loadedAt = s.now()
+	return entries, nil
+}
+
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Time{}
+}
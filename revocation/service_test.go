@@ -0,0 +1,81 @@
+package revocation
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hunoz/maroon-api/store"
+)
+
+// countingStorage counts List calls and fails them while err is set.
+type countingStorage struct {
+	*store.Memory[Entry]
+	lists int
+	err   error
+}
+
+func (c *countingStorage) List() ([]*Entry, error) {
+	c.lists++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c.Memory.List()
+}
+
+func TestIsRevokedCache(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	storage := &countingStorage{Memory: store.NewMemory[Entry]()}
+	service := NewService(&Config{Storage: storage})
+	service.now = func() time.Time { return now }
+
+	// Another instance revokes the token.
+	if err := storage.Put(&Entry{Id: "other", TokenId: "token"}); err != nil {
+		t.Fatal(err)
+	}
+	if !service.IsRevoked("issuer", "token", "alice", now) {
+		t.Error("token revoked in storage is not revoked")
+	}
+	if !service.IsRevoked("issuer", "token", "alice", now) || storage.lists != 1 {
+		t.Errorf("storage was listed %d times, want the entries to be cached", storage.lists)
+	}
+
+	// Revocations made here apply straight away.
+	if _, err := service.Revoke(Entry{Subject: "bob"}, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	if !service.IsRevoked("issuer", "", "bob", now) {
+		t.Error("subject revoked on this instance is not revoked")
+	}
+
+	// Failing reads reject tokens once the cache is stale.
+	storage.err = errors.New("unavailable")
+	if service.IsRevoked("issuer", "other", "carol", now) {
+		t.Error("token was rejected while the cache was fresh")
+	}
+	now = now.Add(cacheTTL)
+	if !service.IsRevoked("issuer", "other", "carol", now) {
+		t.Error("token was accepted while revocations could not be read")
+	}
+}
+
+func TestRevokeExpiry(t *testing.T) {
+	service := NewService(&Config{MaxTokenLifetime: time.Hour})
+	issuedBefore := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	entry, err := service.Revoke(Entry{Subject: "alice", IssuedBefore: issuedBefore}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := issuedBefore.Add(time.Hour); !entry.StoreExpiry().Equal(want) {
+		t.Errorf("entry expires at %s, want %s", entry.StoreExpiry(), want)
+	}
+
+	entry, err = service.Revoke(Entry{Subject: "alice"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entry.StoreExpiry().IsZero() {
+		t.Errorf("entry without issuedBefore expires at %s, want it kept", entry.StoreExpiry())
+	}
+}
@@ -0,0 +1,6 @@
+package revocation
+
+import "github.com/hunoz/maroon-api/store"
+
+// Storage persists revocations.
+type Storage = store.Store[Entry]
@@ -0,0 +1,19 @@
+package signing
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/hunoz/maroon-api/ginctx"
+)
+
+var contextKey = ginctx.Key[*Signer]("signing")
+
+// Middleware makes the signer available to the handlers for the request.
+func Middleware(signer *Signer) gin.HandlerFunc {
+	return contextKey.Middleware(signer)
+}
+
+// FromContext returns the request's signer, or nil if there is none.
+func FromContext(ctx *gin.Context) *Signer {
+	signer, _ := contextKey.Get(ctx)
+	return signer
+}
@@ -0,0 +1,39 @@
+package signing
+
+import (
+	"context"
+	"crypto/rsa"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/pkg/errors"
+)
+
+// LoadPrivateKeySecret reads a PEM-encoded RSA private key from a Secrets Manager secret.
+func LoadPrivateKeySecret(secretId string) (*rsa.PrivateKey, error) {
+	cfg, err := config.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating config")
+	}
+	client := secretsmanager.NewFromConfig(cfg)
+
+	output, err := client.GetSecretValue(context.TODO(), &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretId),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error getting signing key")
+	}
+
+	secret := aws.ToString(output.SecretString)
+	if secret == "" {
+		return nil, errors.Errorf("Signing key secret '%s' has no secret string", secretId)
+	}
+
+	key, err := ParsePrivateKey([]byte(secret))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing signing key")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,117 @@
+package signing
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/hunoz/maroon-api/authentication"
+)
+
+// Algorithm is the algorithm Maroon signs its own tokens with.
+const Algorithm = "RS256"
+
+// Signer issues tokens that Maroon itself vouches for.
+type Signer struct {
+	issuer string
+	key    *rsa.PrivateKey
+	kid    string
+}
+
+// NewSigner returns a signer for tokens from issuer, which is the iss claim of every token it signs.
+func NewSigner(issuer string, key *rsa.PrivateKey) (*Signer, error) {
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	thumbprint := sha256.Sum256(der)
+
+	return &Signer{
+		issuer: issuer,
+		key:    key,
+		kid:    base64.RawURLEncoding.EncodeToString(thumbprint[:16]),
+	}, nil
+}
+
+// ParsePrivateKey reads an RSA private key in PEM-encoded PKCS #1 or PKCS #8 form.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("signing key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("signing key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// LoadPrivateKeyFile reads a PEM-encoded RSA private key from a file.
+func LoadPrivateKeyFile(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(data)
+}
+
+// Issuer is the iss claim of the signer's tokens.
+func (s *Signer) Issuer() string {
+	return s.issuer
+}
+
+// Sign adds the iss and iat claims to claims and signs them.
+func (s *Signer) Sign(claims jwt.MapClaims) (string, error) {
+	claims["iss"] = s.issuer
+	claims["iat"] = time.Now().Unix()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.kid
+	return token.SignedString(s.key)
+}
+
+// JWK returns the key set that verifies the signer's tokens.
+func (s *Signer) JWK() *authentication.JWK {
+	return &authentication.JWK{
+		Keys: []authentication.JSONWebKey{{
+			Alg: Algorithm,
+			Kid: s.kid,
+			Kty: "RSA",
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+			N:   base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+		}},
+	}
+}
+
+// IssuerConfig trusts the signer's tokens, verified against its key set without any network access.
+func (s *Signer) IssuerConfig(name string, audiences []string) (authentication.IssuerConfig, error) {
+	jwks, err := json.Marshal(s.JWK())
+	if err != nil {
+		return authentication.IssuerConfig{}, err
+	}
+
+	return authentication.IssuerConfig{
+		Name:          name,
+		PrincipalType: authentication.PrincipalTypeMachine,
+		Issuer:        s.issuer,
+		JWKS:          jwks,
+		Audiences:     audiences,
+		Algorithms:    []string{Algorithm},
+		ClaimMapping:  authentication.ClaimMapping{Username: "sub"},
+	}, nil
+}
@@ -0,0 +1,198 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	pkgerrors "github.com/pkg/errors"
+)
+
+// Attributes of the DynamoDB table. Every kind of item shares the table: kind is its partition
+// key and key its sort key. expiresAt is the table's TTL attribute.
+const (
+	KindAttribute      = "kind"
+	KeyAttribute       = "key"
+	itemAttribute      = "item"
+	ExpiresAtAttribute = "expiresAt"
+)
+
+// DynamoDBClient is the part of the DynamoDB API the store uses.
+type DynamoDBClient interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+}
+
+// DynamoDB keeps items of one kind as JSON in a DynamoDB table, so that every instance shares
+// them. Reads are strongly consistent.
+type DynamoDB[T Item] struct {
+	client DynamoDBClient
+	table  string
+	kind   string
+	now    func() time.Time
+}
+
+func NewDynamoDB[T Item](client DynamoDBClient, table string, kind string) *DynamoDB[T] {
+	return &DynamoDB[T]{client: client, table: table, kind: kind, now: time.Now}
+}
+
+func (d *DynamoDB[T]) Put(item *T) error {
+	attributes, err := d.encode(item)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: aws.String(d.table),
+		Item:      attributes,
+	})
+	return pkgerrors.Wrapf(err, "Error saving %s '%s'", d.kind, (*item).StoreKey())
+}
+
+// Replace makes the write conditional on the stored JSON being that of previous. Items are
+// always written from the same type, so an unchanged item marshals to the same JSON.
+func (d *DynamoDB[T]) Replace(previous *T, item *T) error {
+	attributes, err := d.encode(item)
+	if err != nil {
+		return err
+	}
+	previousData, err := json.Marshal(previous)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:                 aws.String(d.table),
+		Item:                      attributes,
+		ConditionExpression:       aws.String("#item = :previous"),
+		ExpressionAttributeNames:  map[string]string{"#item": itemAttribute},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":previous": &types.AttributeValueMemberS{Value: string(previousData)}},
+	})
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return ErrConflict
+	}
+	return pkgerrors.Wrapf(err, "Error saving %s '%s'", d.kind, (*item).StoreKey())
+}
+
+func (d *DynamoDB[T]) Get(key string) (*T, error) {
+	output, err := d.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName:      aws.String(d.table),
+		Key:            d.key(key),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "Error getting %s '%s'", d.kind, key)
+	}
+	if output.Item == nil {
+		return nil, ErrNotFound
+	}
+	return d.decode(output.Item)
+}
+
+func (d *DynamoDB[T]) Delete(key string) error {
+	_, err := d.client.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName:                aws.String(d.table),
+		Key:                      d.key(key),
+		ConditionExpression:      aws.String("attribute_exists(#key)"),
+		ExpressionAttributeNames: map[string]string{"#key": KeyAttribute},
+	})
+	return d.deleteError(err, key)
+}
+
+func (d *DynamoDB[T]) List() ([]*T, error) {
+	paginator := dynamodb.NewQueryPaginator(d.client, &dynamodb.QueryInput{
+		TableName:                 aws.String(d.table),
+		KeyConditionExpression:    aws.String("#kind = :kind"),
+		ExpressionAttributeNames:  map[string]string{"#kind": KindAttribute},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":kind": &types.AttributeValueMemberS{Value: d.kind}},
+		ConsistentRead:            aws.Bool(true),
+	})
+
+	items := []*T{}
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, pkgerrors.Wrapf(err, "Error listing %s items", d.kind)
+		}
+		for _, attributes := range page.Items {
+			item, err := d.decode(attributes)
+			if errors.Is(err, ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+	}
+	sortItems(items)
+	return items, nil
+}
+
+func (d *DynamoDB[T]) Take(key string) (*T, error) {
+	output, err := d.client.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+		TableName:                aws.String(d.table),
+		Key:                      d.key(key),
+		ConditionExpression:      aws.String("attribute_exists(#key)"),
+		ExpressionAttributeNames: map[string]string{"#key": KeyAttribute},
+		ReturnValues:             types.ReturnValueAllOld,
+	})
+	if err = d.deleteError(err, key); err != nil {
+		return nil, err
+	}
+	return d.decode(output.Attributes)
+}
+
+func (d *DynamoDB[T]) key(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		KindAttribute: &types.AttributeValueMemberS{Value: d.kind},
+		KeyAttribute:  &types.AttributeValueMemberS{Value: key},
+	}
+}
+
+func (d *DynamoDB[T]) encode(item *T) (map[string]types.AttributeValue, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	attributes := d.key((*item).StoreKey())
+	attributes[itemAttribute] = &types.AttributeValueMemberS{Value: string(data)}
+	if expiring, ok := any(*item).(Expiring); ok && !expiring.StoreExpiry().IsZero() {
+		attributes[ExpiresAtAttribute] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expiring.StoreExpiry().Unix(), 10)}
+	}
+	return attributes, nil
+}
+
+// decode returns the item, or ErrNotFound if it has expired but DynamoDB has not deleted it yet.
+func (d *DynamoDB[T]) decode(attributes map[string]types.AttributeValue) (*T, error) {
+	data, ok := attributes[itemAttribute].(*types.AttributeValueMemberS)
+	if !ok {
+		return nil, pkgerrors.Errorf("%s item has no '%s' attribute", d.kind, itemAttribute)
+	}
+
+	item := new(T)
+	if err := json.Unmarshal([]byte(data.Value), item); err != nil {
+		return nil, pkgerrors.Wrapf(err, "Error parsing %s item", d.kind)
+	}
+	if expired(*item, d.now()) {
+		return nil, ErrNotFound
+	}
+	return item, nil
+}
+
+func (d *DynamoDB[T]) deleteError(err error, key string) error {
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return ErrNotFound
+	}
+	return pkgerrors.Wrapf(err, "Error deleting %s '%s'", d.kind, key)
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// File keeps items in memory and writes all of them to a JSON file on every change.
+type File[T Item] struct {
+	*Memory[T]
+	path string
+}
+
+func NewFile[T Item](path string) (*File[T], error) {
+	f := &File[T]{Memory: NewMemory[T](), path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []T
+	if err = json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		f.items[item.StoreKey()] = item
+	}
+
+	return f, nil
+}
+
+func (f *File[T]) Put(item *T) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.prune()
+	f.items[(*item).StoreKey()] = *item
+	return f.write()
+}
+
+func (f *File[T]) Replace(previous *T, item *T) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.unchanged(previous); err != nil {
+		return err
+	}
+	f.items[(*item).StoreKey()] = *item
+	return f.write()
+}
+
+func (f *File[T]) Delete(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, exists := f.items[key]; !exists {
+		return ErrNotFound
+	}
+	delete(f.items, key)
+	return f.write()
+}
+
+func (f *File[T]) Take(key string) (*T, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, exists := f.items[key]; !exists {
+		return nil, ErrNotFound
+	}
+	item, err := f.take(key)
+	if writeErr := f.write(); writeErr != nil {
+		return nil, writeErr
+	}
+	return item, err
+}
+
+// write saves every item to the file. The caller must hold the lock.
+func (f *File[T]) write() error {
+	data, err := json.MarshalIndent(f.sorted(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, data, 0600)
+}
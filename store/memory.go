@@ -0,0 +1,117 @@
+package store
+
+import (
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Memory keeps items in memory for the lifetime of the process.
+type Memory[T Item] struct {
+	mu    sync.RWMutex
+	items map[string]T
+	now   func() time.Time
+}
+
+func NewMemory[T Item]() *Memory[T] {
+	return &Memory[T]{items: map[string]T{}, now: time.Now}
+}
+
+func (m *Memory[T]) Put(item *T) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prune()
+	m.items[(*item).StoreKey()] = *item
+	return nil
+}
+
+func (m *Memory[T]) Get(key string) (*T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	item, exists := m.items[key]
+	if !exists || expired(item, m.now()) {
+		return nil, ErrNotFound
+	}
+	return &item, nil
+}
+
+func (m *Memory[T]) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.items[key]; !exists {
+		return ErrNotFound
+	}
+	delete(m.items, key)
+	return nil
+}
+
+func (m *Memory[T]) Replace(previous *T, item *T) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.unchanged(previous); err != nil {
+		return err
+	}
+	m.items[(*item).StoreKey()] = *item
+	return nil
+}
+
+func (m *Memory[T]) List() ([]*T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.sorted(), nil
+}
+
+func (m *Memory[T]) Take(key string) (*T, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.take(key)
+}
+
+// take deletes and returns an item. The caller must hold the lock.
+func (m *Memory[T]) take(key string) (*T, error) {
+	item, exists := m.items[key]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	delete(m.items, key)
+	if expired(item, m.now()) {
+		return nil, ErrNotFound
+	}
+	return &item, nil
+}
+
+// unchanged returns ErrConflict unless the stored item is still previous. The caller must hold
+// the lock.
+func (m *Memory[T]) unchanged(previous *T) error {
+	item, exists := m.items[(*previous).StoreKey()]
+	if !exists || expired(item, m.now()) || !reflect.DeepEqual(item, *previous) {
+		return ErrConflict
+	}
+	return nil
+}
+
+// prune drops expired items. The caller must hold the lock.
+func (m *Memory[T]) prune() {
+	now := m.now()
+	for key, item := range m.items {
+		if expired(item, now) {
+			delete(m.items, key)
+		}
+	}
+}
+
+// sorted returns copies of the items that have not expired, oldest first. The caller must
+// hold the lock.
+func (m *Memory[T]) sorted() []*T {
+	now := m.now()
+	items := make([]*T, 0, len(m.items))
+	for _, item := range m.items {
+		if expired(item, now) {
+			continue
+		}
+		item := item
+		items = append(items, &item)
+	}
+	sortItems(items)
+	return items
+}
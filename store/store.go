@@ -0,0 +1,64 @@
+package store
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned by Replace when the item was changed since it was read.
+	ErrConflict = errors.New("item was changed by someone else")
+)
+
+// Item is a record kept in a Store.
+type Item interface {
+	// StoreKey identifies the item.
+	StoreKey() string
+	// StoreOrder sorts items in List, oldest first.
+	StoreOrder() time.Time
+}
+
+// Expiring is an Item that is only kept until StoreExpiry. Expired items are neither returned
+// nor listed, and stores drop them in the background where they can.
+type Expiring interface {
+	StoreExpiry() time.Time
+}
+
+// Store persists items of one kind. Get, Delete and Take return ErrNotFound for unknown keys.
+type Store[T Item] interface {
+	Put(item *T) error
+	Get(key string) (*T, error)
+	Delete(key string) error
+	List() ([]*T, error)
+	// Take deletes and returns an item, so that only one caller ever gets it.
+	Take(key string) (*T, error)
+	// Replace saves item only if the stored item is still previous, as it was read with Get, so
+	// that of two callers changing the same item only the first succeeds. Otherwise it returns
+	// ErrConflict.
+	Replace(previous *T, item *T) error
+}
+
+// NewId returns a random ID for a new item.
+func NewId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func sortItems[T Item](items []*T) {
+	sort.Slice(items, func(i, j int) bool {
+		return (*items[i]).StoreOrder().Before((*items[j]).StoreOrder())
+	})
+}
+
+// expired reports whether item is Expiring and past its expiry.
+func expired[T Item](item T, now time.Time) bool {
+	expiring, ok := any(item).(Expiring)
+	return ok && !expiring.StoreExpiry().IsZero() && !now.Before(expiring.StoreExpiry())
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type testItem struct {
+	Id        string    `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (i testItem) StoreKey() string {
+	return i.Id
+}
+
+func (i testItem) StoreOrder() time.Time {
+	return i.CreatedAt
+}
+
+func (i testItem) StoreExpiry() time.Time {
+	return i.ExpiresAt
+}
+
+// fakeDynamoDB keeps items by kind and key, and only understands the requests the store makes.
+type fakeDynamoDB struct {
+	mu    sync.Mutex
+	items map[string]map[string]types.AttributeValue
+}
+
+func newFakeDynamoDB() *fakeDynamoDB {
+	return &fakeDynamoDB{items: map[string]map[string]types.AttributeValue{}}
+}
+
+func fakeKey(key map[string]types.AttributeValue) string {
+	return key[KindAttribute].(*types.AttributeValueMemberS).Value + "/" + key[KeyAttribute].(*types.AttributeValueMemberS).Value
+}
+
+func (f *fakeDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := fakeKey(params.Item)
+	if previous, ok := params.ExpressionAttributeValues[":previous"]; ok {
+		stored, exists := f.items[key][itemAttribute].(*types.AttributeValueMemberS)
+		if !exists || stored.Value != previous.(*types.AttributeValueMemberS).Value {
+			return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+		}
+	}
+	f.items[key] = params.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &dynamodb.GetItemOutput{Item: f.items[fakeKey(params.Key)]}, nil
+}
+
+func (f *fakeDynamoDB) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := fakeKey(params.Key)
+	item, exists := f.items[key]
+	if !exists {
+		return nil, &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+	}
+	delete(f.items, key)
+	return &dynamodb.DeleteItemOutput{Attributes: item}, nil
+}
+
+func (f *fakeDynamoDB) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kind := params.ExpressionAttributeValues[":kind"].(*types.AttributeValueMemberS).Value
+	output := &dynamodb.QueryOutput{}
+	for _, item := range f.items {
+		if item[KindAttribute].(*types.AttributeValueMemberS).Value == kind {
+			output.Items = append(output.Items, item)
+		}
+	}
+	return output, nil
+}
+
+func TestStores(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	client := newFakeDynamoDB()
+	file, err := NewFile[testItem](filepath.Join(t.TempDir(), "items.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.now = clock
+	memory := NewMemory[testItem]()
+	memory.now = clock
+	dynamo := NewDynamoDB[testItem](client, "table", "test")
+	dynamo.now = clock
+	// Another kind in the same table must not be seen.
+	other := NewDynamoDB[testItem](client, "table", "other")
+	if err := other.Put(&testItem{Id: "a", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, s := range map[string]Store[testItem]{"memory": memory, "file": file, "dynamodb": dynamo} {
+		t.Run(name, func(t *testing.T) {
+			for _, item := range []testItem{
+				{Id: "b", CreatedAt: now.Add(-time.Minute)},
+				{Id: "a", CreatedAt: now.Add(-2 * time.Minute), ExpiresAt: now.Add(time.Minute)},
+				{Id: "expired", CreatedAt: now.Add(-3 * time.Minute), ExpiresAt: now},
+			} {
+				item := item
+				if err := s.Put(&item); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if _, err := s.Get("expired"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get(expired) error = %v, want ErrNotFound", err)
+			}
+			if item, err := s.Get("b"); err != nil || item.Id != "b" {
+				t.Errorf("Get(b) = %v, %v", item, err)
+			}
+
+			items, err := s.List()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != 2 || items[0].Id != "a" || items[1].Id != "b" {
+				t.Errorf("List() = %v, want a and b, oldest first", items)
+			}
+
+			previous, err := s.Get("b")
+			if err != nil {
+				t.Fatal(err)
+			}
+			changed := *previous
+			changed.ExpiresAt = now.Add(time.Hour)
+			if err := s.Replace(previous, &changed); err != nil {
+				t.Errorf("Replace(b) error = %v", err)
+			}
+			if err := s.Replace(previous, &changed); !errors.Is(err, ErrConflict) {
+				t.Errorf("second Replace(b) error = %v, want ErrConflict", err)
+			}
+			if item, err := s.Get("b"); err != nil || !item.ExpiresAt.Equal(changed.ExpiresAt) {
+				t.Errorf("Get(b) after Replace = %v, %v", item, err)
+			}
+			if err := s.Replace(&testItem{Id: "missing"}, &testItem{Id: "missing"}); !errors.Is(err, ErrConflict) {
+				t.Errorf("Replace(missing) error = %v, want ErrConflict", err)
+			}
+
+			if item, err := s.Take("a"); err != nil || item.Id != "a" {
+				t.Errorf("Take(a) = %v, %v", item, err)
+			}
+			if _, err := s.Take("a"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second Take(a) error = %v, want ErrNotFound", err)
+			}
+
+			if err := s.Delete("b"); err != nil {
+				t.Errorf("Delete(b) error = %v", err)
+			}
+			if err := s.Delete("b"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second Delete(b) error = %v, want ErrNotFound", err)
+			}
+			if _, err := s.Get("b"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get(b) after Delete error = %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.json")
+	file, err := NewFile[testItem](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Put(&testItem{Id: "a", CreatedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewFile[testItem](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item, err := reloaded.Get("a"); err != nil || item.Id != "a" {
+		t.Errorf("Get(a) after reload = %v, %v", item, err)
+	}
+}